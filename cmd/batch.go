@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batch limits; both may be set in config
+const (
+	defaultBatchMaxItems    = 50
+	defaultBatchConcurrency = 4
+)
+
+// BatchSuggestionResult contains the outcome of one item of a batch request
+type BatchSuggestionResult struct {
+	Status   int                 `json:"status"`
+	Response *SuggestionResponse `json:"response"`
+}
+
+// BatchSuggestionResponse contains one result per request, in request order
+type BatchSuggestionResponse struct {
+	Results []BatchSuggestionResult `json:"results"`
+	Errors  []SuggestionError       `json:"errors,omitempty"`
+}
+
+// V1BatchSuggestionResult contains the outcome of one item of a v1 batch request
+type V1BatchSuggestionResult struct {
+	Status   int                   `json:"status"`
+	Response *V1SuggestionResponse `json:"response"`
+}
+
+// V1BatchSuggestionResponse contains one v1 result per request, in request order
+type V1BatchSuggestionResponse struct {
+	Results []V1BatchSuggestionResult `json:"results"`
+	Errors  []SuggestionError         `json:"errors,omitempty"`
+}
+
+func (svc *ServiceContext) batchMaxItems() int {
+	if svc.config.Service.BatchMaxItems > 0 {
+		return svc.config.Service.BatchMaxItems
+	}
+	return defaultBatchMaxItems
+}
+
+func (svc *ServiceContext) batchConcurrency() int {
+	if svc.config.Service.BatchConcurrency > 0 {
+		return svc.config.Service.BatchConcurrency
+	}
+	return defaultBatchConcurrency
+}
+
+// processBatchRequest runs every request in the body with bounded concurrency.
+// Items share a cache, so repeated knowledge base lookups and catalog verifications
+// are only performed once per batch.
+func (svc *ServiceContext) processBatchRequest(c *gin.Context) (*BatchSuggestionResponse, int) {
+	base := InitializeSuggestion(svc, c)
+
+	var reqs []SuggestionRequest
+
+	if err := c.ShouldBindJSON(&reqs); err != nil {
+		log.Printf("invalid batch request: %s", err.Error())
+		errs := []SuggestionError{{Code: errCodeInvalidRequest, Source: "request", Message: err.Error()}}
+		return &BatchSuggestionResponse{Results: []BatchSuggestionResult{}, Errors: errs}, http.StatusBadRequest
+	}
+
+	if len(reqs) == 0 || len(reqs) > svc.batchMaxItems() {
+		msg := fmt.Sprintf("batch must contain between 1 and %d requests", svc.batchMaxItems())
+		errs := []SuggestionError{{Code: errCodeInvalidRequest, Source: "request", Message: msg}}
+		return &BatchSuggestionResponse{Results: []BatchSuggestionResult{}, Errors: errs}, http.StatusBadRequest
+	}
+
+	start := time.Now()
+	log.Printf("[BATCH] processing %d requests (concurrency=%d)", len(reqs), svc.batchConcurrency())
+
+	cache := newSuggestionCache()
+	results := make([]BatchSuggestionResult, len(reqs))
+	sem := make(chan struct{}, svc.batchConcurrency())
+
+	var wg sync.WaitGroup
+
+	for i, req := range reqs {
+		wg.Add(1)
+		go func(i int, req SuggestionRequest) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			s := &SuggestionContext{
+				svc:     svc,
+				req:     req,
+				verbose: base.verbose,
+				claims:  base.claims,
+				cache:   cache,
+			}
+
+			if errs := s.normalizeAndValidate(); len(errs) > 0 {
+				res := &SuggestionResponse{Suggestions: []Suggestion{}, Errors: errs}
+				results[i] = BatchSuggestionResult{Status: statusForErrors(errs), Response: res}
+				return
+			}
+
+			res, status := s.runSuggestionRequest(false)
+			results[i] = BatchSuggestionResult{Status: status, Response: res}
+		}(i, req)
+	}
+
+	wg.Wait()
+
+	log.Printf("[BATCH] finished %d requests (took %v)", len(reqs), time.Since(start))
+
+	return &BatchSuggestionResponse{Results: results}, http.StatusOK
+}
+
+// BatchSuggestionHandler suggests alternate searches for several queries in one call
+func (svc *ServiceContext) BatchSuggestionHandler(c *gin.Context) {
+	res, status := svc.processBatchRequest(c)
+	c.JSON(status, res)
+}
+
+// V1BatchSuggestionHandler suggests alternate searches for several queries in one call,
+// using the v1 response contract
+func (svc *ServiceContext) V1BatchSuggestionHandler(c *gin.Context) {
+	res, status := svc.processBatchRequest(c)
+
+	v1 := &V1BatchSuggestionResponse{Results: []V1BatchSuggestionResult{}, Errors: res.Errors}
+	for _, r := range res.Results {
+		v1.Results = append(v1.Results, V1BatchSuggestionResult{Status: r.Status, Response: toV1Response(r.Response)})
+	}
+
+	c.JSON(status, v1)
+}
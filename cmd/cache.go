@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryCache is a simple in-memory cache.  Concurrent lookups of the same key
+// share a single computation, and failed computations are not cached.
+// A ttl of zero means entries never expire.
+type memoryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*memoryCacheEntry
+	pruned  time.Time
+}
+
+type memoryCacheEntry struct {
+	once    sync.Once
+	val     interface{}
+	err     error
+	expires time.Time // zero if the entry never expires
+}
+
+func newMemoryCache(ttl time.Duration) *memoryCache {
+	return &memoryCache{ttl: ttl, entries: make(map[string]*memoryCacheEntry)}
+}
+
+// getOrCompute returns the cached value for key, computing it if necessary
+func (c *memoryCache) getOrCompute(key string, compute func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok == true && entry.expires.IsZero() == false && time.Now().After(entry.expires) {
+		ok = false
+	}
+	if ok == false {
+		c.prune()
+		entry = &memoryCacheEntry{}
+		if c.ttl > 0 {
+			entry.expires = time.Now().Add(c.ttl)
+		}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.val, entry.err = compute()
+	})
+
+	if entry.err != nil {
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+
+	return entry.val, entry.err
+}
+
+// prune drops expired entries, at most once per ttl, so that a long-lived cache does not
+// grow without bound.  The caller must hold the lock.
+func (c *memoryCache) prune() {
+	if c.ttl == 0 || time.Since(c.pruned) < c.ttl {
+		return
+	}
+
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.pruned = now
+}
+
+// suggestionCache holds results that can safely be shared between suggestion
+// requests, such as the items of a batch request
+type suggestionCache struct {
+	retrievals    *memoryCache
+	verifications *memoryCache
+}
+
+func newSuggestionCache() *suggestionCache {
+	return &suggestionCache{
+		retrievals:    newMemoryCache(0),
+		verifications: newMemoryCache(0),
+	}
+}
@@ -12,8 +12,11 @@ import (
 const envPrefix = "VIRGO4_SUGGESTOR_WS"
 
 type serviceConfigService struct {
-	Port   string `json:"port,omitempty"`
-	JWTKey string `json:"jwt_key,omitempty"`
+	Port             string `json:"port,omitempty"`
+	JWTKey           string `json:"jwt_key,omitempty"`
+	MaxQueryLength   int    `json:"max_query_length,omitempty"`
+	BatchMaxItems    int    `json:"batch_max_items,omitempty"`
+	BatchConcurrency int    `json:"batch_concurrency,omitempty"`
 }
 
 type serviceConfigSolrParams struct {
@@ -24,14 +27,28 @@ type serviceConfigSolrParams struct {
 	Sort    string   `json:"sort,omitempty"`
 }
 
+// serviceConfigLimits sets how much of each suggestion type is retrieved, generated and returned
+type serviceConfigLimits struct {
+	Limit      int `json:"returned,omitempty"`   // suggestions returned
+	Retrieve   int `json:"retrieve,omitempty"`   // knowledge base or Solr hits retrieved as context
+	Candidates int `json:"candidates,omitempty"` // suggestions requested from the LLM
+}
+
 type serviceConfigSuggestion struct {
-	Limit  int                     `json:"limit,omitempty"`
-	Params serviceConfigSolrParams `json:"params,omitempty"`
+	serviceConfigLimits
+	Max         serviceConfigLimits     `json:"max,omitempty"`         // the most a request may ask for
+	LegacyLimit int                     `json:"limit,omitempty"`       // authors returned by /suggest/authors; defaults to the author output limit
+	MinCount    int                     `json:"min_count,omitempty"`   // drop suggestions with fewer catalog records
+	CountField  string                  `json:"count_field,omitempty"` // catalog field records are counted on
+	Params      serviceConfigSolrParams `json:"params,omitempty"`
 }
 
 type serviceConfigSuggestionTypes struct {
-	Author serviceConfigSuggestion `json:"author,omitempty"`
-	Book   serviceConfigSuggestion `json:"book,omitempty"`
+	Author  serviceConfigSuggestion `json:"author,omitempty"`
+	Book    serviceConfigSuggestion `json:"book,omitempty"`
+	Image   serviceConfigSuggestion `json:"image,omitempty"`
+	Subject serviceConfigSuggestion `json:"subject,omitempty"`
+	Series  serviceConfigSuggestion `json:"series,omitempty"`
 }
 
 type serviceConfigSolrClient struct {
@@ -46,9 +63,70 @@ type serviceConfigSolrClients struct {
 }
 
 type serviceConfigSolr struct {
-	Host    string                   `json:"host,omitempty"`
-	Core    string                   `json:"core,omitempty"`
-	Clients serviceConfigSolrClients `json:"clients,omitempty"`
+	Host        string                   `json:"host,omitempty"`
+	Core        string                   `json:"core,omitempty"`
+	CatalogCore string                   `json:"catalog_core,omitempty"`
+	Clients     serviceConfigSolrClients `json:"clients,omitempty"`
+}
+
+type serviceConfigPrompt struct {
+	ID          string `json:"id,omitempty"`
+	Description string `json:"description,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Book        string `json:"book,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+}
+
+type serviceConfigFilters struct {
+	Facets     map[string]string   `json:"facets,omitempty"`      // Virgo4 facet ID -> catalog field
+	Pools      map[string][]string `json:"pools,omitempty"`       // Virgo4 pool ID -> catalog fq
+	ViewFields map[string]string   `json:"view_fields,omitempty"` // suggestion type -> catalog field
+}
+
+type serviceConfigRanking struct {
+	Method           string             `json:"method,omitempty"` // weighted (default) or rrf
+	RRFK             int                `json:"rrf_k,omitempty"`
+	RetrievalWeight  float64            `json:"retrieval_weight,omitempty"`
+	LLMWeight        float64            `json:"llm_weight,omitempty"`
+	SimilarityWeight float64            `json:"similarity_weight,omitempty"`
+	PopularityWeight float64            `json:"popularity_weight,omitempty"`
+	SourceWeight     float64            `json:"source_weight,omitempty"`
+	SourceWeights    map[string]float64 `json:"source_weights,omitempty"`
+}
+
+type serviceConfigDiversify struct {
+	Pseudonyms [][]string `json:"pseudonyms,omitempty"` // groups of names used by the same person
+}
+
+// serviceConfigDidYouMean configures the local spelling corrector that answers did-you-mean
+// before the LLM is asked
+type serviceConfigDidYouMean struct {
+	Disabled        bool     `json:"disabled,omitempty"`
+	MaxPhrases      int      `json:"max_phrases,omitempty"`      // autocomplete phrases loaded, most common first
+	MaxEdits        int      `json:"max_edits,omitempty"`        // the most edits a correction may make to a word
+	MinCount        int      `json:"min_count,omitempty"`        // corrections must appear in at least this many records
+	ConfidenceRatio float64  `json:"confidence_ratio,omitempty"` // how much more common a correction must be than the runner-up
+	RefreshHours    int      `json:"refresh_hours,omitempty"`
+	Fq              []string `json:"fq,omitempty"` // restricts the phrases loaded, e.g. to certain types
+	Qf              string   `json:"qf,omitempty"` // catalog fields searched when counting hits; the core's default if empty
+}
+
+// serviceConfigGraph configures the related-author graph built from catalog facets
+type serviceConfigGraph struct {
+	Disabled          bool `json:"disabled,omitempty"`
+	MaxAuthors        int  `json:"max_authors,omitempty"`         // authors in the graph, most records first
+	Neighbours        int  `json:"neighbours,omitempty"`          // links kept per author
+	MinShared         int  `json:"min_shared,omitempty"`          // records co-authors must share
+	MinSharedSubjects int  `json:"min_shared_subjects,omitempty"` // subjects authors must share
+	RefreshHours      int  `json:"refresh_hours,omitempty"`
+	Concurrency       int  `json:"concurrency,omitempty"` // catalog queries in flight while building
+}
+
+// serviceConfigAuthorPanel configures the author knowledge panel endpoint
+type serviceConfigAuthorPanel struct {
+	CacheMinutes int `json:"cache_minutes,omitempty"`
+	Works        int `json:"works,omitempty"`   // notable works listed
+	Related      int `json:"related,omitempty"` // related authors listed
 }
 
 type serviceConfigAI struct {
@@ -61,12 +139,25 @@ type serviceConfigAI struct {
 	BooksKnowledgeBaseID   string `json:"books_knowledge_base_id,omitempty"`
 	GuardrailID            string `json:"guardrail_id,omitempty"`
 	GuardrailVersion       string `json:"guardrail_version,omitempty"`
+	Prompts                []serviceConfigPrompt `json:"prompts,omitempty"`
+	Retriever              serviceConfigRetriever `json:"retriever,omitempty"`
+}
+
+type serviceConfigRetriever struct {
+	Type  string `json:"type,omitempty"`  // provider (the AI provider's knowledge bases) or local
+	Index string `json:"index,omitempty"` // local index directory, as written by the export command
 }
 
 type serviceConfig struct {
 	Service     serviceConfigService         `json:"service,omitempty"`
 	Solr        serviceConfigSolr            `json:"solr,omitempty"`
 	Suggestions serviceConfigSuggestionTypes `json:"suggestions,omitempty"`
+	Filters     serviceConfigFilters         `json:"filters,omitempty"`
+	Ranking     serviceConfigRanking         `json:"ranking,omitempty"`
+	Diversify   serviceConfigDiversify       `json:"diversify,omitempty"`
+	DidYouMean  serviceConfigDidYouMean      `json:"didyoumean,omitempty"`
+	AuthorPanel serviceConfigAuthorPanel     `json:"author_panel,omitempty"`
+	Graph       serviceConfigGraph           `json:"graph,omitempty"`
 	AI          serviceConfigAI              `json:"ai,omitempty"`
 }
 
@@ -118,6 +209,9 @@ func loadConfig() *serviceConfig {
 	if cfg.Solr.Core == "" {
 		cfg.Solr.Core = "autocomplete"
 	}
+	if cfg.Solr.CatalogCore == "" {
+		cfg.Solr.CatalogCore = "test_core"
+	}
 	if cfg.Solr.Clients.Service.Endpoint == "" {
 		cfg.Solr.Clients.Service.Endpoint = "select"
 	}
@@ -125,6 +219,127 @@ func loadConfig() *serviceConfig {
 		cfg.Solr.Clients.HealthCheck.Endpoint = "admin/ping"
 	}
 
+	for _, t := range suggestionLimitTypes {
+		cfg.Suggestions.forType(t).applyLimitDefaults(defaultSuggestionLimits[t], defaultSuggestionMaxLimits[t])
+	}
+
+	// subject headings live alongside author phrases in the autocomplete core
+	if cfg.Suggestions.Subject.Params.DefType == "" {
+		cfg.Suggestions.Subject.Params.DefType = "edismax"
+	}
+	if len(cfg.Suggestions.Subject.Params.Fl) == 0 {
+		cfg.Suggestions.Subject.Params.Fl = []string{"phrase", "count", "score"}
+	}
+	if len(cfg.Suggestions.Subject.Params.Fq) == 0 {
+		cfg.Suggestions.Subject.Params.Fq = []string{"type:subject"}
+	}
+	if cfg.Suggestions.Subject.Params.Qf == "" {
+		cfg.Suggestions.Subject.Params.Qf = cfg.Suggestions.Author.Params.Qf
+	}
+	if cfg.Suggestions.Subject.Params.Sort == "" {
+		cfg.Suggestions.Subject.Params.Sort = "score desc, count desc"
+	}
+
+	if cfg.Suggestions.Book.CountField == "" {
+		cfg.Suggestions.Book.CountField = "title_f"
+	}
+
+	// series and uniform titles are found in the catalog core
+	if cfg.Suggestions.Series.Params.DefType == "" {
+		cfg.Suggestions.Series.Params.DefType = "edismax"
+	}
+	if len(cfg.Suggestions.Series.Params.Fl) == 0 {
+		cfg.Suggestions.Series.Params.Fl = []string{"id", "title_a", "title_display", "title_series_f", "title_uniform_f", "score"}
+	}
+	if cfg.Suggestions.Series.Params.Qf == "" {
+		cfg.Suggestions.Series.Params.Qf = "title_series_t^5 title_uniform_t^3 title_t"
+	}
+
+	if cfg.Filters.Facets == nil {
+		cfg.Filters.Facets = defaultFilterFacets
+	}
+	if cfg.Filters.Pools == nil {
+		cfg.Filters.Pools = defaultFilterPools
+	}
+	if cfg.Filters.ViewFields == nil {
+		cfg.Filters.ViewFields = defaultFilterViewFields
+	}
+
+	// ranking weights are taken as a set; if none are given, use the defaults
+	r := &cfg.Ranking
+	if r.RetrievalWeight == 0 && r.LLMWeight == 0 && r.SimilarityWeight == 0 && r.PopularityWeight == 0 && r.SourceWeight == 0 {
+		r.RetrievalWeight = 0.35
+		r.LLMWeight = 0.2
+		r.SimilarityWeight = 0.2
+		r.PopularityWeight = 0.1
+		r.SourceWeight = 0.15
+	}
+	if r.Method == "" {
+		r.Method = rankMethodWeighted
+	}
+	if r.Method != rankMethodWeighted && r.Method != rankMethodRRF {
+		log.Printf("[CONFIG] unknown ranking method [%s]; using %s", r.Method, rankMethodWeighted)
+		r.Method = rankMethodWeighted
+	}
+	if r.RRFK == 0 {
+		r.RRFK = defaultRRFK
+	}
+	if r.SourceWeights == nil {
+		r.SourceWeights = defaultSourceWeights
+	}
+
+	if cfg.Diversify.Pseudonyms == nil {
+		cfg.Diversify.Pseudonyms = defaultPseudonyms
+	}
+
+	d := &cfg.DidYouMean
+	if d.MaxPhrases == 0 {
+		d.MaxPhrases = defaultSpellerMaxPhrases
+	}
+	if d.MaxEdits == 0 {
+		d.MaxEdits = defaultSpellerMaxEdits
+	}
+	if d.MinCount == 0 {
+		d.MinCount = defaultSpellerMinCount
+	}
+	if d.ConfidenceRatio == 0 {
+		d.ConfidenceRatio = defaultSpellerConfidenceRatio
+	}
+	if d.RefreshHours == 0 {
+		d.RefreshHours = defaultSpellerRefreshHours
+	}
+
+	g := &cfg.Graph
+	if g.MaxAuthors == 0 {
+		g.MaxAuthors = defaultGraphMaxAuthors
+	}
+	if g.Neighbours == 0 {
+		g.Neighbours = defaultGraphNeighbours
+	}
+	if g.MinShared == 0 {
+		g.MinShared = defaultGraphMinShared
+	}
+	if g.MinSharedSubjects == 0 {
+		g.MinSharedSubjects = defaultGraphMinSharedSubjects
+	}
+	if g.RefreshHours == 0 {
+		g.RefreshHours = defaultGraphRefreshHours
+	}
+	if g.Concurrency == 0 {
+		g.Concurrency = defaultGraphConcurrency
+	}
+
+	p := &cfg.AuthorPanel
+	if p.CacheMinutes == 0 {
+		p.CacheMinutes = defaultPanelCacheMinutes
+	}
+	if p.Works == 0 {
+		p.Works = defaultPanelWorks
+	}
+	if p.Related == 0 {
+		p.Related = defaultPanelRelated
+	}
+
 	if host := os.Getenv(envPrefix + "_SOLR_HOST"); host != "" {
 		cfg.Solr.Host = host
 	}
@@ -152,6 +367,10 @@ func loadConfig() *serviceConfig {
 	if grVer := os.Getenv(envPrefix + "_GUARDRAIL_VERSION"); grVer != "" {
 		cfg.AI.GuardrailVersion = grVer
 	}
+	if index := os.Getenv(envPrefix + "_LOCAL_INDEX"); index != "" {
+		cfg.AI.Retriever.Type = "local"
+		cfg.AI.Retriever.Index = index
+	}
 
 	// Default AI config if not provided
 	if cfg.AI.Provider == "" {
@@ -160,6 +379,9 @@ func loadConfig() *serviceConfig {
 	if cfg.AI.Model == "" {
 		cfg.AI.Model = "google.gemma-3-4b-it"
 	}
+	if cfg.AI.Retriever.Type == "" {
+		cfg.AI.Retriever.Type = "provider"
+	}
 	if cfg.AI.KnowledgeBaseID == "" {
 		cfg.AI.KnowledgeBaseID = "ANITQDQQXN"
 	}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// GetBookResourceCounts retrieves the number of catalog records carrying each of the given
+// titles (editions, printings and copies), within the current view if the request has filters
+func (s *SuggestionContext) GetBookResourceCounts(titles []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(titles) == 0 {
+		return counts, nil
+	}
+
+	field := s.svc.config.Suggestions.Book.CountField
+
+	var queryParts []string
+	for _, t := range titles {
+		queryParts = append(queryParts, quoteSolrValue(t))
+	}
+
+	solrReq := SolrRequest{Core: s.svc.config.Solr.CatalogCore}
+	solrReq.json.Params = SolrRequestParams{
+		Q:          "*:*",
+		Rows:       0,
+		Fq:         append([]string{fmt.Sprintf("%s:(%s)", field, strings.Join(queryParts, " OR "))}, s.filterQueries()...),
+		Facet:      true,
+		FacetField: []string{field},
+		FacetLimit: -1,
+		FacetMin:   1,
+	}
+
+	solrRes, err := s.SolrQuery(&solrReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// facet values may differ in case from the suggested titles
+	found := make(map[string]int)
+	values := solrRes.FacetCounts.FacetFields[field]
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := values[i].(string)
+		count, _ := values[i+1].(float64)
+		found[strings.ToLower(name)] += int(count)
+	}
+
+	for _, t := range titles {
+		counts[t] = found[strings.ToLower(t)]
+	}
+
+	return counts, nil
+}
+
+// attachResourceCounts sets the catalog record count on every author and book suggestion,
+// then drops any whose count is below the configured minimum for its type.
+// Counts that cannot be fetched are left alone, and nothing is dropped for them.
+func (s *SuggestionContext) attachResourceCounts(res *SuggestionResponse) {
+	var authors []string
+	for _, a := range res.Authors {
+		// verification already found the autocomplete count, but that ignores any filters
+		if a.Count == 0 || s.hasFilters() == true {
+			authors = append(authors, a.Facet)
+		}
+	}
+
+	var titles []string
+	for _, b := range res.Books {
+		titles = append(titles, b.Value)
+	}
+
+	authorCounts, err := s.GetAuthorResourceCounts(authors)
+	if err != nil {
+		log.Printf("[COUNTS] author counts failed: %s", err.Error())
+		s.addWarning("counts", err)
+		authorCounts = nil
+	}
+
+	bookCounts, err := s.GetBookResourceCounts(titles)
+	if err != nil {
+		log.Printf("[COUNTS] book counts failed: %s", err.Error())
+		s.addWarning("counts", err)
+		bookCounts = nil
+	}
+
+	counted := func(sugg *Suggestion) bool {
+		switch sugg.Type {
+		case "author":
+			if authorCounts == nil {
+				return false
+			}
+			if count, ok := authorCounts[sugg.Facet]; ok == true || s.hasFilters() == true {
+				sugg.Count = count
+			}
+			return true
+		case "book":
+			if bookCounts == nil {
+				return false
+			}
+			sugg.Count = bookCounts[sugg.Value]
+			return true
+		case "subject":
+			return true
+		}
+		return false
+	}
+
+	// each suggestion appears in both the combined list and its type list; only log once
+	keep := func(sugg *Suggestion, logDrop bool) bool {
+		if counted(sugg) == false {
+			return true
+		}
+
+		minCount := s.minCount(sugg.Type)
+		if sugg.Count < minCount {
+			if logDrop == true {
+				log.Printf("[COUNTS] DROPPED %s '%s' (%d records, minimum %d)", sugg.Type, sugg.Value, sugg.Count, minCount)
+			}
+			return false
+		}
+
+		return true
+	}
+
+	filter := func(list []Suggestion, logDrops bool) []Suggestion {
+		kept := list[:0]
+		for i := range list {
+			if keep(&list[i], logDrops) == true {
+				kept = append(kept, list[i])
+			}
+		}
+		return kept
+	}
+
+	res.Authors = filter(res.Authors, false)
+	res.Books = filter(res.Books, false)
+	res.Subjects = filter(res.Subjects, false)
+	res.Suggestions = filter(res.Suggestions, true)
+}
+
+// minCount returns the minimum catalog record count for a suggestion type
+func (s *SuggestionContext) minCount(suggType string) int {
+	switch suggType {
+	case "author":
+		return s.svc.config.Suggestions.Author.MinCount
+	case "book":
+		return s.svc.config.Suggestions.Book.MinCount
+	case "subject":
+		return s.svc.config.Suggestions.Subject.MinCount
+	}
+	return 0
+}
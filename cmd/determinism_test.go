@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// jitter sleeps for a few milliseconds, so concurrent work finishes in a different order each run
+func jitter() {
+	time.Sleep(time.Duration(rand.Intn(4)) * time.Millisecond)
+}
+
+// jitterProvider is the contract provider with several suggestions of each type, answered
+// after a random delay
+type jitterProvider struct {
+	contractProvider
+}
+
+func (p jitterProvider) suggestions(suggType string, values ...string) *providers.AIResponse {
+	jitter()
+	res := &providers.AIResponse{}
+	for _, v := range values {
+		res.Suggestions = append(res.Suggestions, providers.AIResponseSuggestion{Name: v, Type: suggType, Reason: "determinism test"})
+	}
+	return res
+}
+
+func (p jitterProvider) GetAuthorSuggestions(query string, customPrompt string, suggContext providers.SuggestionContextData, debug bool) (*providers.AIResponse, error) {
+	return p.suggestions("author", "Twain, Mark", "Harte, Bret", "Howells, William Dean", "Clemens, Samuel"), nil
+}
+
+func (p jitterProvider) GetBookSuggestions(query string, customPrompt string, suggContext providers.SuggestionContextData, debug bool) (*providers.AIResponse, error) {
+	return p.suggestions("book", "Roughing it", "The Gilded Age", "Life on the Mississippi"), nil
+}
+
+func (p jitterProvider) GetSubjectSuggestions(query string, customPrompt string, suggContext providers.SuggestionContextData, debug bool) (*providers.AIResponse, error) {
+	return p.suggestions("subject", "Humorists, American", "Mississippi River"), nil
+}
+
+func (p jitterProvider) Retrieve(query string, limit int, threshold float64) ([]providers.AuthorHit, error) {
+	jitter()
+	return []providers.AuthorHit{
+		{Name: "Twain, Mark", FacetLabel: "Twain, Mark", Score: 0.9},
+		{Name: "Harte, Bret", FacetLabel: "Harte, Bret", Score: 0.9},
+	}, nil
+}
+
+// echoSolr answers each Solr request, after a random delay, with a document whose phrase
+// and title are the query itself, so every suggestion verifies as itself.  All documents
+// have the same count, so ranking relies on its tie-breaks.
+func echoSolr(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jitter()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req SolrRequestJSON
+		json.NewDecoder(r.Body).Decode(&req)
+
+		q := strings.ReplaceAll(req.Params.Q, `\`, "")
+		if req.Params.Rows == 0 || q == "" || q == "*:*" {
+			fmt.Fprint(w, `{"responseHeader": {"status": 0}, "response": {"numFound": 3, "docs": []}, "facet_counts": {"facet_fields": {}}}`)
+			return
+		}
+
+		doc, _ := json.Marshal(map[string]interface{}{"id": "id-" + q, "phrase": q, "title_a": []string{q}, "count": 3, "score": 1.0})
+		fmt.Fprintf(w, `{"responseHeader": {"status": 0}, "response": {"numFound": 1, "maxScore": 1.0, "docs": [%s]}, "facet_counts": {"facet_fields": {}}}`, doc)
+	}))
+}
+
+// TestDeterministicOrder checks that repeated runs of the same request return the same
+// suggestions in the same order, however the concurrent lookups interleave
+func TestDeterministicOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := contractService(t)
+	svc.AIProvider = jitterProvider{}
+	svc.Retriever = svc.AIProvider
+
+	solr := echoSolr(t)
+	t.Cleanup(solr.Close)
+	svc.solr.service.host = solr.URL
+
+	router := gin.New()
+	svc.registerAPIRoutes(router.Group(apiPrefix))
+
+	body := `{"query": "keyword: {mark twain}", "features": ["author", "book", "subject", "images", "series"]}`
+
+	var first []string
+	for run := 0; run < 10; run++ {
+		req := httptest.NewRequest(http.MethodPost, apiPrefix+"/suggest", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("run %d: status %d, want %d; body: %s", run, w.Code, http.StatusOK, w.Body.String())
+		}
+
+		var res SuggestionResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("run %d: decoding response: %v", run, err)
+		}
+
+		var got []string
+		for _, sugg := range res.Suggestions {
+			got = append(got, fmt.Sprintf("%s|%s|%s|%.4f", sugg.Type, sugg.Value, sugg.Source, sugg.Score))
+		}
+
+		if first == nil {
+			if len(got) < 5 {
+				t.Fatalf("run %d: only %d suggestions, want several of each type: %q", run, len(got), got)
+			}
+			first = got
+			continue
+		}
+
+		if reflect.DeepEqual(got, first) == false {
+			t.Errorf("run %d: suggestions %q, want %q", run, got, first)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// DidYouMeanHits contains the catalog hit counts of the query and its suggested correction
+type DidYouMeanHits struct {
+	Query      int `json:"query"`
+	DidYouMean int `json:"did_you_mean"`
+}
+
+// catalogHits counts the catalog records a search for all the words of the query finds,
+// within the current view, sharing results through the request cache
+func (s *SuggestionContext) catalogHits(query string) (int, error) {
+	cfg := s.svc.config.DidYouMean
+
+	retrieve := func() (interface{}, error) {
+		solrReq := SolrRequest{Core: s.svc.config.Solr.CatalogCore}
+		solrReq.json.Params = SolrRequestParams{
+			Rows:    0,
+			DefType: "edismax",
+			Q:       escapeSolrQuery(query),
+			Qf:      cfg.Qf,
+			Mm:      "100%",
+			Fq:      s.filterQueries(),
+		}
+
+		solrRes, err := s.SolrQuery(&solrReq)
+		if err != nil {
+			return nil, err
+		}
+
+		return solrRes.Response.NumFound, nil
+	}
+
+	hits, err := s.cachedRetrieval(fmt.Sprintf("hits|%s|%s", s.filterKey(), strings.ToLower(query)), retrieve)
+	if err != nil {
+		return 0, err
+	}
+
+	return hits.(int), nil
+}
+
+// isKnownPhrase reports whether the query is, ignoring case, a phrase in the autocomplete core
+func (s *SuggestionContext) isKnownPhrase(query string) (bool, error) {
+	solrReq := SolrRequest{}
+	solrReq.json.Params = SolrRequestParams{
+		Rows: 10,
+		Q:    "phrase:" + quoteSolrValue(query),
+		Fl:   []string{"phrase"},
+	}
+
+	solrRes, err := s.SolrQuery(&solrReq)
+	if err != nil {
+		return false, err
+	}
+
+	for _, doc := range solrRes.Response.Docs {
+		if strings.EqualFold(strings.TrimSpace(doc.Phrase), strings.TrimSpace(query)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// verifyDidYouMean checks a suggested correction against the catalog.  The correction is
+// only worth offering if it finds more records than the query, or if it is a known phrase
+// that finds any records at all.  It returns the hit counts of both, and whether to offer it;
+// a correction that cannot be checked is not offered.
+func (s *SuggestionContext) verifyDidYouMean(query string, correction string) (*DidYouMeanHits, bool) {
+	var hits DidYouMeanHits
+	var known bool
+	var queryErr, correctionErr, phraseErr error
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		hits.Query, queryErr = s.catalogHits(query)
+	}()
+	go func() {
+		defer wg.Done()
+		hits.DidYouMean, correctionErr = s.catalogHits(correction)
+	}()
+	go func() {
+		defer wg.Done()
+		known, phraseErr = s.isKnownPhrase(correction)
+	}()
+	wg.Wait()
+
+	for _, err := range []error{queryErr, correctionErr} {
+		if err != nil {
+			log.Printf("[DYM] hit count failed: %s", err.Error())
+			s.addWarning("didyoumean", err)
+			return nil, false
+		}
+	}
+
+	// the phrase check only adds to the evidence, so its failure is not fatal
+	if phraseErr != nil {
+		log.Printf("[DYM] phrase check failed: %s", phraseErr.Error())
+	}
+
+	switch {
+	case hits.DidYouMean > hits.Query:
+		log.Printf("[DYM] '%s' (%d hits) improves on '%s' (%d hits)", correction, hits.DidYouMean, query, hits.Query)
+	case known == true && hits.DidYouMean > 0:
+		log.Printf("[DYM] '%s' (%d hits) is a known phrase; '%s' has %d hits", correction, hits.DidYouMean, query, hits.Query)
+	default:
+		log.Printf("[DYM] DROPPED '%s' (%d hits): no improvement on '%s' (%d hits)", correction, hits.DidYouMean, query, hits.Query)
+		return &hits, false
+	}
+
+	return &hits, true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestDidYouMeanSource checks that a correction reports where it came from in both the
+// original and the v1 responses, without debug
+func TestDidYouMeanSource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := contractService(t)
+
+	router := gin.New()
+	svc.registerAPIRoutes(router.Group(apiPrefix))
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, apiPrefix+path, strings.NewReader(`{"query": "keyword: {mark twian}", "features": ["didyoumean"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, want %d; body: %s", path, w.Code, http.StatusOK, w.Body.String())
+		}
+		return w
+	}
+
+	var res SuggestionResponse
+	if err := json.Unmarshal(post("/suggest").Body.Bytes(), &res); err != nil {
+		t.Fatalf("decoding /suggest: %v", err)
+	}
+	if res.DidYouMean != "mark twain" || res.DidYouMeanSource != dymSourceLLM {
+		t.Errorf("/suggest did_you_mean = %q from %q, want %q from %q", res.DidYouMean, res.DidYouMeanSource, "mark twain", dymSourceLLM)
+	}
+
+	var v1 V1SuggestionResponse
+	if err := json.Unmarshal(post("/v1/suggest").Body.Bytes(), &v1); err != nil {
+		t.Fatalf("decoding /v1/suggest: %v", err)
+	}
+	if v1.DidYouMean == nil {
+		t.Fatalf("/v1/suggest has no did_you_mean")
+	}
+	if v1.DidYouMean.Query != "mark twain" || v1.DidYouMean.Source != dymSourceLLM || v1.DidYouMean.Hits != 1 || v1.DidYouMean.QueryHits != 0 {
+		t.Errorf("/v1/suggest did_you_mean = %+v, want mark twain from %s with 1 hit over 0", *v1.DidYouMean, dymSourceLLM)
+	}
+}
+
+// TestDidYouMeanNoCorrection checks that a query the local dictionary accepts as spelled
+// correctly gets no did-you-mean, and so no source for one
+func TestDidYouMeanNoCorrection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := contractService(t)
+	svc.spellCorrector = testSpeller()
+
+	router := gin.New()
+	svc.registerAPIRoutes(router.Group(apiPrefix))
+
+	req := httptest.NewRequest(http.MethodPost, apiPrefix+"/suggest", strings.NewReader(`{"query": "keyword: {mark twain}", "features": ["didyoumean"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var res SuggestionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decoding /suggest: %v", err)
+	}
+	if res.DidYouMean != "" || res.DidYouMeanSource != "" {
+		t.Errorf("/suggest did_you_mean = %q from %q, want none", res.DidYouMean, res.DidYouMeanSource)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/uvalib/virgo4-suggestor-ws/names"
+)
+
+// defaultPseudonyms links well-known pseudonyms to the names they stand for; the list
+// may be replaced in config
+var defaultPseudonyms = [][]string{
+	{"Twain, Mark", "Clemens, Samuel Langhorne"},
+	{"Orwell, George", "Blair, Eric Arthur"},
+	{"Eliot, George", "Evans, Mary Ann"},
+	{"Carroll, Lewis", "Dodgson, Charles Lutwidge"},
+	{"Seuss, Dr.", "Geisel, Theodor Seuss"},
+	{"Le Carré, John", "Cornwell, David John Moore"},
+	{"Brontë, Charlotte", "Bell, Currer"},
+	{"Voltaire", "Arouet, François-Marie"},
+}
+
+// personKey returns the key an author suggestion is grouped on: the pseudonym group it
+// belongs to, if any, otherwise its name key.  A name belongs to a group only if it is
+// one of the group's names (see names.Matches), so other people who share some of the
+// words, such as "Eliot, George Fielding" or "Evans, Mary", are not grouped with them.
+func (s *SuggestionContext) personKey(name string) string {
+	for i, group := range s.svc.config.Diversify.Pseudonyms {
+		for _, member := range group {
+			if names.Matches(name, member) == true {
+				return fmt.Sprintf("pseudonym|%d", i)
+			}
+		}
+	}
+
+	return names.Key(name)
+}
+
+// diversifySuggestions collapses author suggestions that are variant forms of the same
+// person's name (with and without dates, or under a known pseudonym) into one suggestion.
+// The best ranked form is kept, and the facets of the others are listed as its alternates.
+// It expects the suggestions to be ranked already.
+func (s *SuggestionContext) diversifySuggestions(res *SuggestionResponse) {
+	if len(res.Suggestions) == 0 {
+		return
+	}
+
+	var kept []Suggestion
+	people := make(map[string]int)
+
+	for _, sugg := range res.Suggestions {
+		if sugg.Type != "author" {
+			kept = append(kept, sugg)
+			continue
+		}
+
+		facet := sugg.Facet
+		if facet == "" {
+			facet = sugg.Value
+		}
+
+		key := s.personKey(facet)
+
+		if i, ok := people[key]; ok == true {
+			log.Printf("[DIVERSIFY] '%s' is a variant of '%s'", facet, kept[i].Value)
+			kept[i].Alternates = append(kept[i].Alternates, facet)
+			continue
+		}
+
+		people[key] = len(kept)
+		kept = append(kept, sugg)
+	}
+
+	res.Suggestions = kept
+	res.splitByType()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func pseudonymContext() *SuggestionContext {
+	cfg := &serviceConfig{Diversify: serviceConfigDiversify{Pseudonyms: defaultPseudonyms}}
+	return &SuggestionContext{svc: &ServiceContext{config: cfg}}
+}
+
+func TestPersonKey(t *testing.T) {
+	s := pseudonymContext()
+
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		// variant forms of one name
+		{"Twain, Mark, 1835-1910", "Mark Twain", true},
+		{"Twain, Mark, 1835-1910", "Twain, M.", true},
+		{"García Márquez, Gabriel, 1927-2014", "Gabriel Garcia Marquez", true},
+
+		// pseudonyms
+		{"Twain, Mark, 1835-1910", "Clemens, Samuel Langhorne, 1835-1910", true},
+		{"Eliot, George, 1819-1880", "Evans, Mary Ann", true},
+		{"Voltaire, 1694-1778", "Arouet, François-Marie", true},
+		{"Voltaire", "Arouet, Francois-Marie, 1694-1778", true},
+		{"Le Carre, John, 1931-2020", "Cornwell, David John Moore", true},
+		{"Seuss, Dr.", "Dr. Seuss", true},
+		{"Carroll, Lewis, 1832-1898 (author)", "Dodgson, Charles Lutwidge", true},
+
+		// other people who share some of the words
+		{"Eliot, George Fielding, 1894-1971", "Eliot, George, 1819-1880", false},
+		{"Eliot, George Fielding, 1894-1971", "Evans, Mary Ann", false},
+		{"Evans, Mary", "Eliot, George, 1819-1880", false},
+		{"Evans, Mary", "Evans, Mary Ann", false},
+		{"Eliot, T. S. (Thomas Stearns), 1888-1965", "Eliot, George", false},
+		{"Twain, Shania", "Twain, Mark", false},
+		{"Blair, Tony", "Orwell, George", false},
+		{"Carroll, Lewis", "Carroll, Jonathan", false},
+	}
+
+	for _, tt := range tests {
+		ka := s.personKey(tt.a)
+		kb := s.personKey(tt.b)
+		if (ka == kb) != tt.same {
+			t.Errorf("personKey(%q) = %q, personKey(%q) = %q; same person: %v, want %v", tt.a, ka, tt.b, kb, ka == kb, tt.same)
+		}
+	}
+}
+
+func TestPersonKeyPseudonymOnlyForMembers(t *testing.T) {
+	s := pseudonymContext()
+
+	for _, name := range []string{"Eliot, George Fielding, 1894-1971", "Evans, Mary", "Voltaire, Jean", "Twain, Shania"} {
+		if key := s.personKey(name); strings.HasPrefix(key, "pseudonym|") {
+			t.Errorf("personKey(%q) = %q, want a name key", name, key)
+		}
+	}
+
+	for _, name := range []string{"Voltaire", "Voltaire, 1694-1778", "Twain, M.", "Evans, Mary Ann, 1819-1880"} {
+		if key := s.personKey(name); strings.HasPrefix(key, "pseudonym|") == false {
+			t.Errorf("personKey(%q) = %q, want a pseudonym group", name, key)
+		}
+	}
+}
+
+func TestDiversifySuggestions(t *testing.T) {
+	s := pseudonymContext()
+
+	author := func(facet string) Suggestion {
+		return Suggestion{Type: "author", Value: facet, Facet: facet}
+	}
+
+	res := &SuggestionResponse{Suggestions: []Suggestion{
+		author("Eliot, George, 1819-1880"),
+		author("Eliot, George Fielding, 1894-1971"),
+		author("Evans, Mary Ann"),
+		author("Evans, Mary"),
+		author("Twain, Mark, 1835-1910"),
+		author("Twain, M."),
+		{Type: "book", Value: "Middlemarch"},
+	}}
+
+	s.diversifySuggestions(res)
+
+	var got []string
+	alternates := make(map[string][]string)
+	for _, sugg := range res.Suggestions {
+		got = append(got, sugg.Value)
+		if len(sugg.Alternates) > 0 {
+			alternates[sugg.Value] = sugg.Alternates
+		}
+	}
+
+	want := []string{"Eliot, George, 1819-1880", "Eliot, George Fielding, 1894-1971", "Evans, Mary", "Twain, Mark, 1835-1910", "Middlemarch"}
+	if reflect.DeepEqual(got, want) == false {
+		t.Errorf("suggestions = %q, want %q", got, want)
+	}
+
+	wantAlternates := map[string][]string{
+		"Eliot, George, 1819-1880": {"Evans, Mary Ann"},
+		"Twain, Mark, 1835-1910":   {"Twain, M."},
+	}
+	if reflect.DeepEqual(alternates, wantAlternates) == false {
+		t.Errorf("alternates = %q, want %q", alternates, wantAlternates)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// stable error codes reported in the warnings/errors lists of a response
+const (
+	errCodeInvalidRequest        = "invalid_request"
+	errCodeForbidden             = "forbidden"
+	errCodeUnhandledQuery        = "unhandled_query"
+	errCodeDependencyUnavailable = "dependency_unavailable"
+	errCodeGuardrailBlocked      = "guardrail_blocked"
+	errCodeTimeout               = "timeout"
+	errCodeNotFound              = "not_found"
+)
+
+// errorCodePriority orders codes by which one best explains an empty response,
+// along with the HTTP status to use when that code is the reason
+var errorCodePriority = []struct {
+	code   string
+	status int
+}{
+	{errCodeInvalidRequest, http.StatusBadRequest},
+	{errCodeForbidden, http.StatusForbidden},
+	{errCodeGuardrailBlocked, http.StatusUnprocessableEntity},
+	{errCodeUnhandledQuery, http.StatusUnprocessableEntity},
+	{errCodeNotFound, http.StatusNotFound},
+	{errCodeTimeout, http.StatusGatewayTimeout},
+	{errCodeDependencyUnavailable, http.StatusServiceUnavailable},
+}
+
+// errUnhandledQuery is returned when the query cannot be turned into something we can suggest against
+var errUnhandledQuery = errors.New("unhandled query")
+
+// SuggestionError describes a problem encountered while producing suggestions
+type SuggestionError struct {
+	Code    string `json:"code"`
+	Source  string `json:"source,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// errorCodeFor maps an internal error onto one of the stable error codes
+func errorCodeFor(err error) string {
+	if errors.Is(err, providers.ErrGuardrailBlocked) {
+		return errCodeGuardrailBlocked
+	}
+
+	if errors.Is(err, errUnhandledQuery) {
+		return errCodeUnhandledQuery
+	}
+
+	if errors.Is(err, errPromptForbidden) {
+		return errCodeForbidden
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errCodeTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errCodeTimeout
+	}
+
+	return errCodeDependencyUnavailable
+}
+
+// statusForErrors picks the HTTP status for a response whose errors left it with nothing useful
+func statusForErrors(errs []SuggestionError) int {
+	for _, p := range errorCodePriority {
+		for _, e := range errs {
+			if e.Code == p.code {
+				return p.status
+			}
+		}
+	}
+
+	return http.StatusInternalServerError
+}
+
+// addWarning records a non-fatal problem against a part of the suggestion process.
+// Repeated problems of the same kind from the same source are only reported once.
+func (s *SuggestionContext) addWarning(source string, err error) {
+	s.addIssue(SuggestionError{Code: errorCodeFor(err), Source: source, Message: err.Error()})
+}
+
+func (s *SuggestionContext) addIssue(issue SuggestionError) {
+	s.issuesMu.Lock()
+	defer s.issuesMu.Unlock()
+
+	for _, w := range s.issues {
+		if w.Code == issue.Code && w.Source == issue.Source {
+			return
+		}
+	}
+
+	s.issues = append(s.issues, issue)
+}
+
+// finalizeResponse attaches any recorded problems to the response.  If nothing useful
+// was produced, the problems are reported as errors and an error status is returned.
+// Warnings about request fields that were accepted anyway never become errors.
+func (s *SuggestionContext) finalizeResponse(res *SuggestionResponse) int {
+	s.issuesMu.Lock()
+	issues := append([]SuggestionError{}, s.issues...)
+	s.issuesMu.Unlock()
+
+	if len(issues) == 0 {
+		return http.StatusOK
+	}
+
+	if len(res.Suggestions) == 0 && res.DidYouMean == "" {
+		var errs []SuggestionError
+		for _, issue := range issues {
+			if issue.Field != "" {
+				res.Warnings = append(res.Warnings, issue)
+				continue
+			}
+			errs = append(errs, issue)
+		}
+
+		if len(errs) == 0 {
+			return http.StatusOK
+		}
+
+		res.Errors = errs
+		return statusForErrors(errs)
+	}
+
+	res.Warnings = issues
+
+	return http.StatusOK
+}
+
+// errorResponse builds an empty suggestion response carrying a single error,
+// along with the HTTP status it should be sent with
+func errorResponse(code string, source string, err error) (*SuggestionResponse, int) {
+	log.Printf("ERROR: [%s] %s", code, err.Error())
+
+	res := &SuggestionResponse{Suggestions: []Suggestion{}}
+	res.Errors = []SuggestionError{{Code: code, Source: source, Message: err.Error()}}
+
+	return res, statusForErrors(res.Errors)
+}
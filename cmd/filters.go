@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SuggestionFilter is a facet filter active in the user's current search view
+type SuggestionFilter struct {
+	FacetID string `json:"facet_id"`
+	Value   string `json:"value"`
+}
+
+// reFilterRange matches the only range filter values passed to Solr unquoted: years, or
+// * for an open end, e.g. [1900 TO 1950] or [* TO 1800]
+var reFilterRange = regexp.MustCompile(`^\[(\d{4}|\*) TO (\d{4}|\*)\]$`)
+
+// default mappings from Virgo4 facet IDs and pools to the catalog core; both may be
+// replaced in config.  Pools not listed here search the whole catalog core.
+var (
+	defaultFilterFacets = map[string]string{
+		"FacetLibrary":       "library_f",
+		"FacetFormat":        "format_f",
+		"FacetLanguage":      "language_f",
+		"FacetSubject":       "subject_f",
+		"FacetAuthor":        "author_facet_f",
+		"FacetCollection":    "source_f",
+		"FacetPublishedDate": "published_daterange",
+	}
+
+	defaultFilterPools = map[string][]string{
+		"uva_library": {},
+	}
+
+	// catalog fields used to check that a suggestion has records in the current view
+	defaultFilterViewFields = map[string]string{
+		"author":  "author_facet_f",
+		"subject": "subject_f",
+	}
+)
+
+// filterNames returns the keys of a filter config map, sorted for error messages
+func filterNames(m interface{}) []string {
+	var names []string
+	switch v := m.(type) {
+	case map[string]string:
+		for k := range v {
+			names = append(names, k)
+		}
+	case map[string][]string:
+		for k := range v {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateFilters normalizes the pool and filters of the request, reporting unknown ones
+func (s *SuggestionContext) validateFilters(fieldError func(field string, format string, args ...interface{})) {
+	req := &s.req
+	cfg := s.svc.config.Filters
+
+	// pools without configured clauses add no filters, so every Virgo4 pool is accepted,
+	// but the caller is warned that the suggestions are not limited to that pool
+	req.Pool = strings.TrimSpace(req.Pool)
+	if _, ok := cfg.Pools[req.Pool]; req.Pool != "" && ok == false {
+		s.addIssue(SuggestionError{Code: errCodeInvalidRequest, Source: "filters", Field: "pool",
+			Message: fmt.Sprintf("pool [%s] has no configured filters, so suggestions are not limited to it; pools with configured filters are: %s", req.Pool, strings.Join(filterNames(cfg.Pools), ", "))})
+	}
+
+	var filters []SuggestionFilter
+	for _, f := range req.Filters {
+		f.FacetID = strings.TrimSpace(f.FacetID)
+		f.Value = stripControlCharacters(f.Value)
+
+		if _, ok := cfg.Facets[f.FacetID]; ok == false {
+			fieldError("filters", "unknown facet_id [%s]; valid facet IDs are: %s", f.FacetID, strings.Join(filterNames(cfg.Facets), ", "))
+			continue
+		}
+
+		if f.Value == "" {
+			fieldError("filters", "filter [%s] has no value", f.FacetID)
+			continue
+		}
+
+		if isFilterRange(f.Value) == true && reFilterRange.MatchString(f.Value) == false {
+			fieldError("filters", "filter [%s] has an invalid range [%s]; ranges must look like [1900 TO 1950], with * for an open end", f.FacetID, f.Value)
+			continue
+		}
+
+		filters = append(filters, f)
+	}
+	req.Filters = filters
+}
+
+// isFilterRange reports whether a filter value is written as a range
+func isFilterRange(value string) bool {
+	return strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]")
+}
+
+// hasFilters reports whether the request narrows the catalog view at all
+func (s *SuggestionContext) hasFilters() bool {
+	return len(s.filterQueries()) > 0
+}
+
+// filterQueries converts the pool and filters of the request into catalog core fq clauses
+func (s *SuggestionContext) filterQueries() []string {
+	cfg := s.svc.config.Filters
+
+	fq := []string{}
+	fq = append(fq, cfg.Pools[s.req.Pool]...)
+
+	for _, f := range s.req.Filters {
+		field := cfg.Facets[f.FacetID]
+		if field == "" {
+			continue
+		}
+
+		// only well-formed ranges such as [1900 TO 1950] are passed through unquoted;
+		// anything else is a single value, so it cannot widen the view
+		value := quoteSolrValue(f.Value)
+		if reFilterRange.MatchString(f.Value) == true {
+			value = f.Value
+		}
+
+		fq = append(fq, fmt.Sprintf("%s:%s", field, value))
+	}
+
+	return fq
+}
+
+// filterKey identifies the current view, for keying cached results that depend on it
+func (s *SuggestionContext) filterKey() string {
+	return strings.Join(s.filterQueries(), "|")
+}
+
+// inCurrentView reports whether a suggestion has any catalog records in the view defined
+// by the request pool and filters.  Books are checked by catalog ID; other types by
+// their configured catalog field.  Without filters, everything is in view.
+func (s *SuggestionContext) inCurrentView(suggType string, value string, id string) bool {
+	if s.hasFilters() == false {
+		return true
+	}
+
+	var clause string
+	if suggType == "book" {
+		if id == "" {
+			return false
+		}
+		clause = fmt.Sprintf("id:%s", quoteSolrValue(id))
+	} else {
+		field := s.svc.config.Filters.ViewFields[suggType]
+		if field == "" {
+			// nothing to check this type against
+			return true
+		}
+		clause = fmt.Sprintf("%s:%s", field, quoteSolrValue(value))
+	}
+
+	lookup := func() (interface{}, error) {
+		solrReq := SolrRequest{Core: s.svc.config.Solr.CatalogCore}
+
+		solrReq.json.Params = SolrRequestParams{
+			Start: 0,
+			Rows:  0,
+			Q:     "*:*",
+			Fq:    append([]string{clause}, s.filterQueries()...),
+		}
+
+		solrRes, err := s.SolrQuery(&solrReq)
+		if err != nil {
+			return nil, err
+		}
+
+		return solrRes.Response.NumFound > 0, nil
+	}
+
+	var val interface{}
+	var err error
+
+	key := "view|" + clause + "|" + s.filterKey()
+	if s.cache != nil {
+		val, err = s.cache.verifications.getOrCompute(key, lookup)
+	} else {
+		val, err = lookup()
+	}
+
+	if err != nil {
+		// fail closed, as with verification
+		log.Printf("[CYCLE-3] Solr error checking view for '%s %s': %v (Failing closed)", suggType, value, err)
+		s.addWarning("verification", err)
+		return false
+	}
+
+	if val.(bool) == false {
+		log.Printf("[CYCLE-3] REJECTED '%s %s' (No records in the current view)", suggType, value)
+		return false
+	}
+
+	return true
+}
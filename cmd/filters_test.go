@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func filterContext(req SuggestionRequest) *SuggestionContext {
+	cfg := &serviceConfig{Filters: serviceConfigFilters{Facets: defaultFilterFacets, Pools: defaultFilterPools, ViewFields: defaultFilterViewFields}}
+	return &SuggestionContext{svc: &ServiceContext{config: cfg}, req: req}
+}
+
+func TestValidateFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      SuggestionRequest
+		fields   []string // fields with errors
+		warnings []string // fields with warnings
+		fq       []string
+	}{
+		{"no pool", SuggestionRequest{}, nil, nil, []string{}},
+		{"configured pool", SuggestionRequest{Pool: " uva_library "}, nil, nil, []string{}},
+		{"unconfigured pool", SuggestionRequest{Pool: "articles"}, nil, []string{"pool"}, []string{}},
+		{
+			"known facet", SuggestionRequest{Filters: []SuggestionFilter{{FacetID: "FacetLibrary", Value: "Alderman"}}},
+			nil, nil, []string{`library_f:"Alderman"`},
+		},
+		{
+			"unknown facet", SuggestionRequest{Filters: []SuggestionFilter{{FacetID: "FacetShelf", Value: "A"}, {FacetID: "FacetLibrary", Value: "Alderman"}}},
+			[]string{"filters"}, nil, []string{`library_f:"Alderman"`},
+		},
+		{
+			"bad range", SuggestionRequest{Filters: []SuggestionFilter{{FacetID: "FacetPublishedDate", Value: "[1900 TO now]"}}},
+			[]string{"filters"}, nil, []string{},
+		},
+		{
+			"range", SuggestionRequest{Filters: []SuggestionFilter{{FacetID: "FacetPublishedDate", Value: "[1900 TO *]"}}},
+			nil, nil, []string{"published_daterange:[1900 TO *]"},
+		},
+	}
+
+	for _, tt := range tests {
+		s := filterContext(tt.req)
+
+		var fields []string
+		s.validateFilters(func(field string, format string, args ...interface{}) {
+			fields = append(fields, field)
+		})
+
+		var warnings []string
+		for _, issue := range s.issues {
+			warnings = append(warnings, issue.Field)
+		}
+
+		if reflect.DeepEqual(fields, tt.fields) == false {
+			t.Errorf("%s: errors for %q, want %q", tt.name, fields, tt.fields)
+		}
+		if reflect.DeepEqual(warnings, tt.warnings) == false {
+			t.Errorf("%s: warnings for %q, want %q", tt.name, warnings, tt.warnings)
+		}
+		if got := s.filterQueries(); reflect.DeepEqual(got, tt.fq) == false {
+			t.Errorf("%s: filterQueries = %q, want %q", tt.name, got, tt.fq)
+		}
+	}
+}
+
+// TestFinalizeFieldWarnings checks that a warning about an accepted request field does
+// not turn an empty response into an error
+func TestFinalizeFieldWarnings(t *testing.T) {
+	s := filterContext(SuggestionRequest{Pool: "articles"})
+	s.validateFilters(func(field string, format string, args ...interface{}) {})
+
+	res := &SuggestionResponse{Suggestions: []Suggestion{}}
+	if status := s.finalizeResponse(res); status != http.StatusOK {
+		t.Errorf("status = %d, want %d", status, http.StatusOK)
+	}
+	if len(res.Errors) != 0 || len(res.Warnings) != 1 || res.Warnings[0].Field != "pool" {
+		t.Errorf("errors = %+v, warnings = %+v; want only a pool warning", res.Errors, res.Warnings)
+	}
+
+	s.addIssue(SuggestionError{Code: errCodeDependencyUnavailable, Source: "solr", Message: "down"})
+	res = &SuggestionResponse{Suggestions: []Suggestion{}}
+	if status := s.finalizeResponse(res); status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Source != "solr" || len(res.Warnings) != 1 {
+		t.Errorf("errors = %+v, warnings = %+v; want a solr error and a pool warning", res.Errors, res.Warnings)
+	}
+}
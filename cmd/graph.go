@@ -0,0 +1,429 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uvalib/virgo4-suggestor-ws/names"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// author graph defaults; all but the last three may be set in config
+const (
+	defaultGraphMaxAuthors        = 2000
+	defaultGraphNeighbours        = 10
+	defaultGraphMinShared         = 2
+	defaultGraphMinSharedSubjects = 3
+	defaultGraphRefreshHours      = 24
+	defaultGraphConcurrency       = 4
+	graphFacetLimit               = 25  // co-authors and subjects fetched per author
+	graphMaxSubjectAuthors        = 100 // subjects held by more authors are too general to link them
+	graphSeedLimit                = 3   // knowledge base hits whose neighbours are suggested
+)
+
+// graph relations
+const (
+	graphRelationCoauthor = "coauthor"
+	graphRelationSubject  = "subject"
+)
+
+// graphEdge links an author to a neighbour
+type graphEdge struct {
+	facet    string
+	relation string
+	weight   int      // records shared (coauthor) or subjects shared (subject)
+	subjects []string // for subject links, the headings shared, the author's most used first
+}
+
+// authorGraph links the most prolific catalog authors to their co-authors (authors of the
+// same records) and to other authors writing on the same subjects
+type authorGraph struct {
+	neighbours map[string][]graphEdge // author facet -> neighbours, strongest first
+	keys       map[string][]string    // name key -> author facets
+	builtAt    time.Time
+}
+
+// authorProfile is what the catalog says about one author while the graph is built
+type authorProfile struct {
+	coauthors map[string]int
+	subjects  []string
+}
+
+// reason describes the link in a suggestion reason
+func (e graphEdge) reason(from string) string {
+	if e.relation == graphRelationCoauthor {
+		return fmt.Sprintf("Co-author with %s on %d catalog records", names.Display(from), e.weight)
+	}
+
+	shared := e.subjects
+	if len(shared) > 3 {
+		shared = shared[:3]
+	}
+	return fmt.Sprintf("Writes on the same subjects as %s (%s)", names.Display(from), strings.Join(shared, "; "))
+}
+
+// lookup returns the graph nodes for an author name, matched on its name key
+func (g *authorGraph) lookup(name string) []string {
+	return g.keys[names.Key(name)]
+}
+
+// graph returns the current author graph, or nil if none has been built
+func (svc *ServiceContext) graph() *authorGraph {
+	svc.graphMu.RLock()
+	defer svc.graphMu.RUnlock()
+	return svc.authorGraph
+}
+
+// startGraph builds the author graph in the background, and rebuilds it periodically so
+// that it follows changes to the catalog
+func (svc *ServiceContext) startGraph() {
+	cfg := svc.config.Graph
+	if cfg.Disabled == true {
+		log.Printf("[GRAPH] author graph is disabled")
+		return
+	}
+
+	go func() {
+		for {
+			wait := time.Duration(cfg.RefreshHours) * time.Hour
+			if err := svc.loadGraph(); err != nil {
+				log.Printf("[GRAPH] failed to build author graph (retrying in %v): %s", refreshRetryInterval, err.Error())
+				wait = refreshRetryInterval
+			}
+			time.Sleep(wait)
+		}
+	}()
+}
+
+// loadGraph builds an author graph from catalog facets and replaces the current one with it
+func (svc *ServiceContext) loadGraph() error {
+	cfg := svc.config.Graph
+	s := &SuggestionContext{svc: svc}
+
+	start := time.Now()
+
+	authors, err := s.graphAuthors(cfg.MaxAuthors)
+	if err != nil {
+		return err
+	}
+	if len(authors) == 0 {
+		return fmt.Errorf("no authors found")
+	}
+
+	profiles := make([]*authorProfile, len(authors))
+	failures := 0
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, cfg.Concurrency)
+
+	for i, author := range authors {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, author string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			profile, err := s.authorProfile(author)
+			if err != nil {
+				mu.Lock()
+				failures++
+				mu.Unlock()
+				return
+			}
+			profiles[i] = profile
+		}(i, author)
+	}
+	wg.Wait()
+
+	if failures == len(authors) {
+		return fmt.Errorf("all %d author profiles failed", failures)
+	}
+
+	g := buildAuthorGraph(authors, profiles, cfg)
+
+	svc.graphMu.Lock()
+	svc.authorGraph = g
+	svc.graphMu.Unlock()
+
+	edges := 0
+	for _, n := range g.neighbours {
+		edges += len(n)
+	}
+	log.Printf("[GRAPH] built author graph of %d authors and %d links (%d profiles failed; took %v)", len(g.neighbours), edges, failures, time.Since(start))
+
+	return nil
+}
+
+// graphAuthors returns the authors with the most catalog records, from the autocomplete core
+func (s *SuggestionContext) graphAuthors(max int) ([]string, error) {
+	var authors []string
+
+	for len(authors) < max {
+		solrReq := SolrRequest{}
+		solrReq.json.Params = SolrRequestParams{
+			Start: len(authors),
+			Rows:  min(solrPageSize, max-len(authors)),
+			Q:     "*:*",
+			Fl:    []string{"phrase"},
+			Fq:    []string{"type:author"},
+			Sort:  "count desc",
+		}
+
+		solrRes, err := s.SolrQuery(&solrReq)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range solrRes.Response.Docs {
+			authors = append(authors, doc.Phrase)
+		}
+
+		if len(solrRes.Response.Docs) == 0 || len(authors) >= solrRes.Response.NumFound {
+			break
+		}
+	}
+
+	return authors, nil
+}
+
+// authorProfile facets the catalog records of one author on authors and subjects
+func (s *SuggestionContext) authorProfile(author string) (*authorProfile, error) {
+	cfg := s.svc.config.Graph
+	authorField := s.svc.config.Filters.ViewFields["author"]
+	subjectField := s.svc.config.Filters.ViewFields["subject"]
+
+	solrReq := SolrRequest{Core: s.svc.config.Solr.CatalogCore}
+	solrReq.json.Params = SolrRequestParams{
+		Q:          "*:*",
+		Rows:       0,
+		Fq:         []string{fmt.Sprintf("%s:%s", authorField, quoteSolrValue(author))},
+		Facet:      true,
+		FacetField: []string{authorField, subjectField},
+		FacetLimit: graphFacetLimit,
+		FacetMin:   1,
+	}
+
+	solrRes, err := s.SolrQuery(&solrReq)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &authorProfile{coauthors: make(map[string]int)}
+
+	// facet values come back as a flat [value, count, value, count, ...] list
+	values := solrRes.FacetCounts.FacetFields[authorField]
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := values[i].(string)
+		count, _ := values[i+1].(float64)
+		if name != "" && name != author && int(count) >= cfg.MinShared {
+			profile.coauthors[name] = int(count)
+		}
+	}
+
+	values = solrRes.FacetCounts.FacetFields[subjectField]
+	for i := 0; i+1 < len(values); i += 2 {
+		if name, _ := values[i].(string); name != "" {
+			profile.subjects = append(profile.subjects, name)
+		}
+	}
+
+	return profile, nil
+}
+
+// buildAuthorGraph links authors to their co-authors and, through an index of the subjects
+// they write on, to authors sharing enough subjects.  Subjects shared by many authors say
+// little about any of them, so they are left out of the index.  Co-authors come first
+// among each author's neighbours, then subject links, each strongest first.
+func buildAuthorGraph(authors []string, profiles []*authorProfile, cfg serviceConfigGraph) *authorGraph {
+	g := &authorGraph{
+		neighbours: make(map[string][]graphEdge),
+		keys:       make(map[string][]string),
+		builtAt:    time.Now(),
+	}
+
+	bySubject := make(map[string][]int)
+	for i, p := range profiles {
+		if p == nil {
+			continue
+		}
+		for _, subject := range p.subjects {
+			bySubject[subject] = append(bySubject[subject], i)
+		}
+	}
+
+	for i, author := range authors {
+		p := profiles[i]
+		if p == nil {
+			continue
+		}
+
+		var coauthors []graphEdge
+		for name, count := range p.coauthors {
+			coauthors = append(coauthors, graphEdge{facet: name, relation: graphRelationCoauthor, weight: count})
+		}
+		sortEdges(coauthors)
+
+		shared := make(map[int][]string)
+		for _, subject := range p.subjects {
+			others := bySubject[subject]
+			if len(others) > graphMaxSubjectAuthors {
+				continue
+			}
+			for _, j := range others {
+				if j != i {
+					shared[j] = append(shared[j], subject)
+				}
+			}
+		}
+
+		var related []graphEdge
+		for j, subjects := range shared {
+			if len(subjects) >= cfg.MinSharedSubjects && p.coauthors[authors[j]] == 0 {
+				related = append(related, graphEdge{facet: authors[j], relation: graphRelationSubject, weight: len(subjects), subjects: subjects})
+			}
+		}
+		sortEdges(related)
+
+		edges := append(coauthors, related...)
+		if len(edges) > cfg.Neighbours {
+			edges = edges[:cfg.Neighbours]
+		}
+		if len(edges) == 0 {
+			continue
+		}
+
+		g.neighbours[author] = edges
+		key := names.Key(author)
+		g.keys[key] = append(g.keys[key], author)
+	}
+
+	return g
+}
+
+// sortEdges orders edges strongest first, then by name so the order is stable
+func sortEdges(edges []graphEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].weight != edges[j].weight {
+			return edges[i].weight > edges[j].weight
+		}
+		return edges[i].facet < edges[j].facet
+	})
+}
+
+// graphSeeds returns the graph authors a request is about: those named by the query or its
+// author clauses, otherwise the best knowledge base hits
+func (s *SuggestionContext) graphSeeds(g *authorGraph, query string, kbAuthors []providers.AuthorHit) []string {
+	var seeds []string
+	seen := make(map[string]bool)
+
+	add := func(facets []string) {
+		for _, f := range facets {
+			if seen[f] == false {
+				seen[f] = true
+				seeds = append(seeds, f)
+			}
+		}
+	}
+
+	add(g.lookup(query))
+	for _, f := range s.queryFields {
+		if f.Field == "author" {
+			add(g.lookup(f.Value))
+		}
+	}
+
+	if len(seeds) > 0 {
+		return seeds
+	}
+
+	for _, hit := range kbAuthors {
+		if len(seeds) >= graphSeedLimit {
+			break
+		}
+		if _, ok := g.neighbours[hit.FacetLabel]; ok == true {
+			add([]string{hit.FacetLabel})
+		}
+	}
+
+	return seeds
+}
+
+// graphAuthorHits returns the neighbours of the request's seed authors, for the author
+// prompt and as suggestions, strongest links first
+func (s *SuggestionContext) graphAuthorHits(query string, kbAuthors []providers.AuthorHit) []providers.GraphAuthorHit {
+	g := s.svc.graph()
+	if g == nil {
+		return nil
+	}
+
+	seeds := s.graphSeeds(g, query, kbAuthors)
+
+	var hits []providers.GraphAuthorHit
+	seen := make(map[string]bool)
+	for _, seed := range seeds {
+		seen[seed] = true
+	}
+
+	for _, seed := range seeds {
+		// co-author weights count records and subject weights count headings, so each
+		// relation is scaled by its own strongest link
+		edges := g.neighbours[seed]
+		maxWeight := map[string]int{graphRelationCoauthor: 1, graphRelationSubject: 1}
+		for _, e := range edges {
+			maxWeight[e.relation] = max(maxWeight[e.relation], e.weight)
+		}
+
+		for _, e := range edges {
+			if seen[e.facet] == true {
+				continue
+			}
+			seen[e.facet] = true
+
+			// co-authorship is the stronger evidence, so subject links score at most half
+			score := float64(e.weight) / float64(maxWeight[e.relation])
+			if e.relation == graphRelationSubject {
+				score /= 2
+			}
+
+			hits = append(hits, providers.GraphAuthorHit{
+				Name:     e.facet,
+				LinkedTo: seed,
+				Relation: e.relation,
+				Reason:   e.reason(seed),
+				Score:    score,
+			})
+		}
+	}
+
+	if len(seeds) > 0 {
+		log.Printf("[GRAPH] %d neighbours of %s", len(hits), strings.Join(seeds, "; "))
+	}
+
+	return hits
+}
+
+// graphCandidates turns graph neighbours into author suggestion candidates
+func graphCandidates(hits []providers.GraphAuthorHit, limit int) []Suggestion {
+	var candidates []Suggestion
+
+	for _, hit := range hits {
+		if limit > 0 && len(candidates) >= limit {
+			break
+		}
+		candidates = append(candidates, Suggestion{
+			Type:   "author",
+			Value:  hit.Name,
+			Facet:  hit.Name,
+			Source: "graph",
+			Reason: hit.Reason,
+			Score:  hit.Score,
+		})
+	}
+
+	return candidates
+}
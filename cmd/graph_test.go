@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// testGraph builds a graph of four authors, plus enough others sharing one general subject
+// that it links nobody
+func testGraph() *authorGraph {
+	authors := []string{"Twain, Mark", "Warner, Charles Dudley", "Harte, Bret", "Howells, William Dean"}
+	profiles := []*authorProfile{
+		{coauthors: map[string]int{"Warner, Charles Dudley": 12, "Beard, Dan": 3}, subjects: []string{"Humorists", "Mississippi River", "Frontier", "American fiction"}},
+		{coauthors: map[string]int{"Twain, Mark": 12}, subjects: []string{"Humorists", "Gilded Age", "American fiction"}},
+		{subjects: []string{"Frontier", "Humorists", "Mississippi River", "American fiction"}},
+		{subjects: []string{"Mississippi River", "Frontier", "Realism", "American fiction"}},
+	}
+
+	for i := 0; i < graphMaxSubjectAuthors; i++ {
+		authors = append(authors, fmt.Sprintf("Author %03d", i))
+		profiles = append(profiles, &authorProfile{subjects: []string{"American fiction"}})
+	}
+
+	// an author whose records could not be profiled
+	authors = append(authors, "Unknown, A.")
+	profiles = append(profiles, nil)
+
+	return buildAuthorGraph(authors, profiles, serviceConfigGraph{Neighbours: 10, MinSharedSubjects: 2})
+}
+
+func TestBuildAuthorGraph(t *testing.T) {
+	g := testGraph()
+
+	type edge struct {
+		facet    string
+		relation string
+		weight   int
+		subjects []string
+	}
+
+	edges := func(facet string) []edge {
+		var list []edge
+		for _, e := range g.neighbours[facet] {
+			list = append(list, edge{e.facet, e.relation, e.weight, e.subjects})
+		}
+		return list
+	}
+
+	// co-authors first, then subject links, each strongest first then by name.  "American
+	// fiction" is held by too many authors to link any of them, and Warner, though sharing
+	// subjects with Twain, is only linked as a co-author.
+	want := map[string][]edge{
+		"Twain, Mark": {
+			{"Warner, Charles Dudley", graphRelationCoauthor, 12, nil},
+			{"Beard, Dan", graphRelationCoauthor, 3, nil},
+			{"Harte, Bret", graphRelationSubject, 3, []string{"Humorists", "Mississippi River", "Frontier"}},
+			{"Howells, William Dean", graphRelationSubject, 2, []string{"Mississippi River", "Frontier"}},
+		},
+		"Warner, Charles Dudley": {
+			{"Twain, Mark", graphRelationCoauthor, 12, nil},
+		},
+		"Harte, Bret": {
+			{"Twain, Mark", graphRelationSubject, 3, []string{"Frontier", "Humorists", "Mississippi River"}},
+			{"Howells, William Dean", graphRelationSubject, 2, []string{"Frontier", "Mississippi River"}},
+		},
+		"Howells, William Dean": {
+			{"Harte, Bret", graphRelationSubject, 2, []string{"Mississippi River", "Frontier"}},
+			{"Twain, Mark", graphRelationSubject, 2, []string{"Mississippi River", "Frontier"}},
+		},
+	}
+
+	for facet, w := range want {
+		if got := edges(facet); reflect.DeepEqual(got, w) == false {
+			t.Errorf("neighbours of %q = %+v, want %+v", facet, got, w)
+		}
+	}
+
+	if len(g.neighbours) != len(want) {
+		t.Errorf("graph has %d linked authors, want %d", len(g.neighbours), len(want))
+	}
+
+	if got := g.lookup("Mark Twain"); reflect.DeepEqual(got, []string{"Twain, Mark"}) == false {
+		t.Errorf("lookup(%q) = %q, want %q", "Mark Twain", got, []string{"Twain, Mark"})
+	}
+
+	// the neighbour limit keeps the strongest links
+	limited := buildAuthorGraph([]string{"Twain, Mark"}, []*authorProfile{{coauthors: map[string]int{"A": 5, "B": 9, "C": 7}}}, serviceConfigGraph{Neighbours: 2, MinSharedSubjects: 2})
+	if got := limited.neighbours["Twain, Mark"]; len(got) != 2 || got[0].facet != "B" || got[1].facet != "C" {
+		t.Errorf("limited neighbours = %+v, want B then C", got)
+	}
+}
+
+func TestGraphAuthorHits(t *testing.T) {
+	s := &SuggestionContext{svc: &ServiceContext{authorGraph: testGraph()}}
+
+	type hit struct {
+		name     string
+		relation string
+		score    float64
+	}
+
+	var got []hit
+	for _, h := range s.graphAuthorHits("Mark Twain", nil) {
+		got = append(got, hit{h.Name, h.Relation, math.Round(h.Score*10000) / 10000})
+	}
+
+	// each relation is scaled by its own strongest link, and subject links are then halved
+	want := []hit{
+		{"Warner, Charles Dudley", graphRelationCoauthor, 1},
+		{"Beard, Dan", graphRelationCoauthor, 0.25},
+		{"Harte, Bret", graphRelationSubject, 0.5},
+		{"Howells, William Dean", graphRelationSubject, 0.3333},
+	}
+
+	if reflect.DeepEqual(got, want) == false {
+		t.Errorf("graphAuthorHits = %+v, want %+v", got, want)
+	}
+}
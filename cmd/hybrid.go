@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// cycle 1 merges semantic hits from the knowledge base with lexical hits from Solr,
+// so the LLM sees both close meanings and exact name and title matches
+
+// the retrievers a hit can come from, in the order they are listed in hit sources
+const (
+	hitSourceKB   = "kb"   // semantic (vector) search of the knowledge base
+	hitSourceSolr = "solr" // lexical search of the configured Solr query
+)
+
+// hitSourceHybrid is the suggestion source of a hit both retrievers found
+const hitSourceHybrid = "hybrid"
+
+// number of Solr author hits sampled to decide which of them stand out
+const lexicalAuthorSampleRows = 100
+
+// hitSource returns the suggestion source for a hit found by the given retrievers:
+// hits only the knowledge base found are "kb", hits only Solr found are "solr", and
+// hits both found are "hybrid".  Hits that record no retriever came from the knowledge base.
+func hitSource(sources []string) string {
+	switch len(sources) {
+	case 0:
+		return hitSourceKB
+	case 1:
+		return sources[0]
+	default:
+		return hitSourceHybrid
+	}
+}
+
+// lexicalAuthors runs the configured author query against the autocomplete core,
+// sharing results through the request cache.  Like the author endpoint, only hits
+// that stand out from the rest of the results are kept.  Nothing is returned if no
+// author query fields are configured.
+func (s *SuggestionContext) lexicalAuthors(query string, limit int) ([]providers.AuthorHit, error) {
+	sugg := s.svc.config.Suggestions.Author
+
+	if sugg.Params.Qf == "" {
+		return nil, nil
+	}
+
+	retrieve := func() (interface{}, error) {
+		solrReq := SolrRequest{}
+
+		solrReq.json.Params = SolrRequestParams{
+			Start:   0,
+			Rows:    lexicalAuthorSampleRows,
+			DefType: sugg.Params.DefType,
+			Fl:      sugg.Params.Fl,
+			Fq:      sugg.Params.Fq,
+			Q:       escapeSolrQuery(query),
+			Qf:      sugg.Params.Qf,
+			Sort:    sugg.Params.Sort,
+		}
+
+		solrRes, err := s.SolrQuery(&solrReq)
+		if err != nil {
+			return nil, err
+		}
+
+		hits := []providers.AuthorHit{}
+
+		if len(solrRes.Response.Docs) == 0 {
+			return hits, nil
+		}
+
+		cutoff := s.scoreCutoff(solrRes.Response.Docs)
+
+		for _, doc := range solrRes.Response.Docs {
+			if doc.Score < cutoff || len(hits) >= limit {
+				break
+			}
+			if doc.Phrase == "" {
+				continue
+			}
+			hits = append(hits, providers.AuthorHit{Name: doc.Phrase, FacetLabel: doc.Phrase, Score: doc.Score})
+		}
+
+		return hits, nil
+	}
+
+	val, err := s.cachedRetrieval(fmt.Sprintf("solr-authors|%d|%s", limit, query), retrieve)
+	hits, _ := val.([]providers.AuthorHit)
+
+	return hits, err
+}
+
+// lexicalBooks runs the configured book query against the catalog core, within the
+// user's current view, sharing results through the request cache.  Nothing is
+// returned if no book query fields are configured.
+func (s *SuggestionContext) lexicalBooks(query string, limit int) ([]providers.BookHit, error) {
+	sugg := s.svc.config.Suggestions.Book
+
+	if sugg.Params.Qf == "" {
+		return nil, nil
+	}
+
+	retrieve := func() (interface{}, error) {
+		solrReq := SolrRequest{Core: s.svc.config.Solr.CatalogCore}
+
+		defType := sugg.Params.DefType
+		if defType == "" {
+			defType = "edismax"
+		}
+
+		solrReq.json.Params = SolrRequestParams{
+			Start:   0,
+			Rows:    limit,
+			DefType: defType,
+			Fl:      []string{"id", "title_a", "title_display", "author_a", "score"},
+			Fq:      append(append([]string{}, sugg.Params.Fq...), s.filterQueries()...),
+			Q:       escapeSolrQuery(query),
+			Qf:      sugg.Params.Qf,
+			Sort:    sugg.Params.Sort,
+		}
+
+		solrRes, err := s.SolrQuery(&solrReq)
+		if err != nil {
+			return nil, err
+		}
+
+		hits := []providers.BookHit{}
+		for _, doc := range solrRes.Response.Docs {
+			title := doc.displayTitle()
+			if doc.ID == "" || title == "" {
+				continue
+			}
+			hits = append(hits, providers.BookHit{ID: doc.ID, Title: title, Authors: doc.AuthorA, Score: doc.Score})
+		}
+
+		return hits, nil
+	}
+
+	val, err := s.cachedRetrieval(fmt.Sprintf("solr-books|%d|%s|%s", limit, s.filterKey(), query), retrieve)
+	hits, _ := val.([]providers.BookHit)
+
+	return hits, err
+}
+
+// fuseRanks scores the keys of several ranked lists by reciprocal rank fusion: a key
+// gets 1 / (k + rank) from each list it appears in.  Scores are divided by the best
+// possible score, so a key ranked first in every list scores 1.
+func fuseRanks(lists [][]string, k int) map[string]float64 {
+	scores := make(map[string]float64)
+
+	best := 0.0
+	for _, list := range lists {
+		if len(list) == 0 {
+			continue
+		}
+		best += 1 / float64(k+1)
+
+		seen := make(map[string]bool)
+		rank := 0
+		for _, key := range list {
+			if seen[key] == true {
+				continue
+			}
+			seen[key] = true
+			rank++
+			scores[key] += 1 / float64(k+rank)
+		}
+	}
+
+	if best > 0 {
+		for key := range scores {
+			scores[key] /= best
+		}
+	}
+
+	return scores
+}
+
+// fuseAuthorHits merges knowledge base and Solr author hits, matched on their exact
+// facet, by reciprocal rank fusion.  Each merged hit keeps the knowledge base version
+// (which carries a bio) where there is one, records which retrievers found it, and
+// has its score replaced by the fused score, so scores are on the same scale whether
+// one retriever or both found anything.
+func (s *SuggestionContext) fuseAuthorHits(kbHits, solrHits []providers.AuthorHit, limit int) []providers.AuthorHit {
+	merged := []providers.AuthorHit{}
+	keys := []string{}
+	index := make(map[string]int)
+	lists := make([][]string, 2)
+
+	for l, hits := range [][]providers.AuthorHit{kbHits, solrHits} {
+		source := []string{hitSourceKB, hitSourceSolr}[l]
+
+		for _, hit := range hits {
+			key := strings.ToLower(strings.TrimSpace(hit.FacetLabel))
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(hit.Name))
+			}
+			lists[l] = append(lists[l], key)
+
+			if i, ok := index[key]; ok == true {
+				if merged[i].Sources[len(merged[i].Sources)-1] != source {
+					merged[i].Sources = append(merged[i].Sources, source)
+				}
+				continue
+			}
+
+			hit.Sources = []string{source}
+			index[key] = len(merged)
+			merged = append(merged, hit)
+			keys = append(keys, key)
+		}
+	}
+
+	scores := fuseRanks(lists, s.svc.config.Ranking.RRFK)
+	for i := range merged {
+		merged[i].Score = scores[keys[i]]
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+
+	return merged
+}
+
+// fuseBookHits merges knowledge base and Solr book hits, matched on catalog ID (or
+// title, for hits without one), in the same way as fuseAuthorHits
+func (s *SuggestionContext) fuseBookHits(kbHits, solrHits []providers.BookHit, limit int) []providers.BookHit {
+	merged := []providers.BookHit{}
+	keys := []string{}
+	index := make(map[string]int)
+	lists := make([][]string, 2)
+
+	for l, hits := range [][]providers.BookHit{kbHits, solrHits} {
+		source := []string{hitSourceKB, hitSourceSolr}[l]
+
+		for _, hit := range hits {
+			key := hit.ID
+			if key == "" {
+				key = "title|" + strings.ToLower(strings.TrimSpace(hit.Title))
+			}
+			lists[l] = append(lists[l], key)
+
+			if i, ok := index[key]; ok == true {
+				if merged[i].Sources[len(merged[i].Sources)-1] != source {
+					merged[i].Sources = append(merged[i].Sources, source)
+				}
+				continue
+			}
+
+			hit.Sources = []string{source}
+			index[key] = len(merged)
+			merged = append(merged, hit)
+			keys = append(keys, key)
+		}
+	}
+
+	scores := fuseRanks(lists, s.svc.config.Ranking.RRFK)
+	for i := range merged {
+		merged[i].Score = scores[keys[i]]
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+
+	return merged
+}
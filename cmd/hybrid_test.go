@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+func hybridContext() *SuggestionContext {
+	cfg := &serviceConfig{Ranking: serviceConfigRanking{RRFK: 60}}
+	return &SuggestionContext{svc: &ServiceContext{config: cfg}}
+}
+
+func TestHitSource(t *testing.T) {
+	tests := []struct {
+		sources []string
+		want    string
+	}{
+		{nil, "kb"},
+		{[]string{"kb"}, "kb"},
+		{[]string{"solr"}, "solr"},
+		{[]string{"kb", "solr"}, "hybrid"},
+	}
+
+	for _, tt := range tests {
+		if got := hitSource(tt.sources); got != tt.want {
+			t.Errorf("hitSource(%q) = %q, want %q", tt.sources, got, tt.want)
+		}
+	}
+}
+
+func TestFuseRanks(t *testing.T) {
+	tests := []struct {
+		name  string
+		lists [][]string
+		want  map[string]float64
+	}{
+		{"both lists", [][]string{{"a", "b"}, {"a", "c"}}, map[string]float64{"a": 1, "b": 61.0 / 124, "c": 61.0 / 124}},
+		{"one list", [][]string{{"a", "b"}, nil}, map[string]float64{"a": 1, "b": 61.0 / 62}},
+		{"duplicates count once", [][]string{{"a", "a", "b"}, nil}, map[string]float64{"a": 1, "b": 61.0 / 62}},
+		{"no lists", [][]string{nil, nil}, map[string]float64{}},
+	}
+
+	for _, tt := range tests {
+		got := fuseRanks(tt.lists, 60)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: fuseRanks = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for key, want := range tt.want {
+			if math.Abs(got[key]-want) > 1e-9 {
+				t.Errorf("%s: fuseRanks[%q] = %.4f, want %.4f", tt.name, key, got[key], want)
+			}
+		}
+	}
+}
+
+func TestFuseAuthorHits(t *testing.T) {
+	s := hybridContext()
+
+	twain := providers.AuthorHit{Name: "Mark Twain", FacetLabel: "Twain, Mark, 1835-1910", Bio: "American writer", Score: 0.8}
+	harte := providers.AuthorHit{Name: "Bret Harte", FacetLabel: "Harte, Bret, 1836-1902", Score: 0.6}
+	howells := providers.AuthorHit{Name: "Howells, William Dean, 1837-1920", FacetLabel: "Howells, William Dean, 1837-1920", Score: 14.2}
+	solrTwain := providers.AuthorHit{Name: "Twain, Mark, 1835-1910", FacetLabel: "Twain, Mark, 1835-1910", Score: 21.5}
+
+	type result struct {
+		facet  string
+		source string
+		score  float64
+		hasBio bool
+	}
+
+	results := func(hits []providers.AuthorHit) []result {
+		var res []result
+		for _, h := range hits {
+			res = append(res, result{h.FacetLabel, hitSource(h.Sources), math.Round(h.Score*10000) / 10000, h.Bio != ""})
+		}
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		kb, solr []providers.AuthorHit
+		limit    int
+		want     []result
+	}{
+		{
+			"found by both", []providers.AuthorHit{twain, harte}, []providers.AuthorHit{howells, solrTwain}, 10,
+			[]result{
+				{"Twain, Mark, 1835-1910", "hybrid", 0.9919, true},
+				{"Howells, William Dean, 1837-1920", "solr", 0.5, false},
+				{"Harte, Bret, 1836-1902", "kb", 0.4919, false},
+			},
+		},
+		{
+			"knowledge base only", []providers.AuthorHit{twain, harte}, nil, 10,
+			[]result{
+				{"Twain, Mark, 1835-1910", "kb", 1, true},
+				{"Harte, Bret, 1836-1902", "kb", 0.9839, false},
+			},
+		},
+		{
+			"solr only", nil, []providers.AuthorHit{solrTwain, howells}, 10,
+			[]result{
+				{"Twain, Mark, 1835-1910", "solr", 1, false},
+				{"Howells, William Dean, 1837-1920", "solr", 0.9839, false},
+			},
+		},
+		{
+			"limited", []providers.AuthorHit{twain, harte}, []providers.AuthorHit{howells, solrTwain}, 1,
+			[]result{
+				{"Twain, Mark, 1835-1910", "hybrid", 0.9919, true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := results(s.fuseAuthorHits(tt.kb, tt.solr, tt.limit))
+		if reflect.DeepEqual(got, tt.want) == false {
+			t.Errorf("%s: fuseAuthorHits = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFuseBookHits(t *testing.T) {
+	s := hybridContext()
+
+	kb := []providers.BookHit{
+		{ID: "u1", Title: "Roughing it", Score: 0.7},
+		{Title: "Life on the Mississippi", Score: 0.5},
+	}
+	solr := []providers.BookHit{
+		{ID: "u1", Title: "Roughing it", Score: 9.1},
+		{ID: "u2", Title: "Life on the Mississippi", Score: 8.4},
+	}
+
+	var got []string
+	for _, b := range s.fuseBookHits(kb, solr, 10) {
+		got = append(got, b.ID+"|"+b.Title+"|"+hitSource(b.Sources))
+	}
+
+	// books without a catalog ID are matched on title only against other hits without one
+	want := []string{"u1|Roughing it|hybrid", "|Life on the Mississippi|kb", "u2|Life on the Mississippi|solr"}
+	if reflect.DeepEqual(got, want) == false {
+		t.Errorf("fuseBookHits = %q, want %q", got, want)
+	}
+}
+
+// TestLexicalQueryEscaped checks that the lexical author and book lookups send the query
+// to Solr with its syntax characters escaped, so that they are searched for as words
+func TestLexicalQueryEscaped(t *testing.T) {
+	var queries []string
+	solr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SolrRequestJSON
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding Solr request: %v", err)
+		}
+		queries = append(queries, req.Params.Q)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"responseHeader": {"status": 0}, "response": {"numFound": 0, "docs": []}}`)
+	}))
+	t.Cleanup(solr.Close)
+
+	svc := contractService(t)
+	svc.solr.service.host = solr.URL
+	svc.config.Suggestions.Author.Params.Qf = "phrase"
+	svc.config.Suggestions.Book.Params.Qf = "title_t"
+
+	s := &SuggestionContext{svc: svc}
+	if _, err := s.lexicalAuthors("twain: (mark) OR -clemens", 5); err != nil {
+		t.Fatalf("lexicalAuthors: %v", err)
+	}
+	if _, err := s.lexicalBooks("huck* [finn]", 5); err != nil {
+		t.Fatalf("lexicalBooks: %v", err)
+	}
+
+	want := []string{`twain\: \(mark\) OR \-clemens`, `huck\* \[finn\]`}
+	if reflect.DeepEqual(queries, want) == false {
+		t.Errorf("Solr queries = %q, want %q", queries, want)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// languageNames maps the ISO 639-1 codes that can be detected to their English names
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// scriptLanguages maps non-Latin scripts to the language they most likely indicate
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	code   string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// languageStopwords are common function words that identify languages written in Latin script
+var languageStopwords = map[string][]string{
+	"en": {"the", "of", "and", "in", "to", "for", "on", "with", "from", "by", "an", "is", "about"},
+	"es": {"el", "la", "los", "las", "de", "del", "y", "en", "por", "para", "con", "una", "un", "sobre"},
+	"fr": {"le", "la", "les", "des", "du", "de", "et", "en", "pour", "avec", "une", "un", "sur", "dans", "au", "aux"},
+	"de": {"der", "die", "das", "und", "von", "den", "mit", "für", "im", "ein", "eine", "zur", "zum", "über", "des"},
+	"it": {"il", "lo", "gli", "le", "di", "del", "della", "e", "per", "con", "una", "un", "nella", "sulla"},
+	"pt": {"o", "os", "as", "do", "da", "dos", "das", "e", "em", "para", "com", "uma", "um", "no", "na", "sobre"},
+}
+
+// languageLetters are letters that are characteristic of one language in Latin script
+var languageLetters = map[rune]string{
+	'ñ': "es",
+	'¿': "es",
+	'¡': "es",
+	'ã': "pt",
+	'õ': "pt",
+	'ß': "de",
+	'ä': "de",
+	'ö': "de",
+	'ü': "de",
+	'œ': "fr",
+	'ê': "fr",
+	'è': "fr",
+	'ì': "it",
+	'ò': "it",
+}
+
+// languageSuffixes are word endings typical of one language in Latin script.  They are
+// too loose to identify a language alone, so only break ties between languages that
+// share function words, such as Spanish and French "de" and "la".
+var languageSuffixes = map[string][]string{
+	"en": {"ing", "ness", "ship", "ly", "th", "ies"},
+	"es": {"ción", "ciones", "dad", "ia", "ía", "rra", "ismo", "ado", "ada"},
+	"fr": {"eau", "eaux", "aux", "ique", "erre", "ette", "elle", "ement", "ent", "ans", "eur", "eurs", "ure", "ance", "ée", "ées"},
+	"de": {"ung", "keit", "heit", "lich", "isch", "schaft", "chen"},
+	"it": {"zione", "zioni", "ità", "mente", "ello", "ella", "aggio"},
+	"pt": {"ção", "ções", "dade", "ão", "inho", "inha", "eira"},
+}
+
+// latinLanguages are the languages detected from Latin script, in the order they are preferred
+var latinLanguages = []string{"en", "es", "fr", "de", "it", "pt"}
+
+// detectLanguage guesses the language of a query, from its script if that is not Latin,
+// otherwise from the common words and letters it uses.  Queries with no clear signal,
+// such as most bare names, are left undetermined (an empty code).
+func detectLanguage(query string) providers.QueryLanguage {
+	lang := func(code string) providers.QueryLanguage {
+		return providers.QueryLanguage{Code: code, Name: languageNames[code]}
+	}
+
+	// count letters by script
+	scripts := make(map[string]int)
+	latin := 0
+	for _, r := range query {
+		if unicode.IsLetter(r) == false {
+			continue
+		}
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				scripts[s.code]++
+				break
+			}
+		}
+	}
+
+	// kana marks Japanese even when most characters are Han
+	if scripts["ja"] > 0 {
+		return lang("ja")
+	}
+
+	best := ""
+	for _, s := range scriptLanguages {
+		if scripts[s.code] > scripts[best] {
+			best = s.code
+		}
+	}
+
+	if best != "" && scripts[best] >= latin {
+		// Ukrainian has letters Russian does not
+		if best == "ru" && strings.ContainsAny(strings.ToLower(query), "іїєґ") {
+			return lang("uk")
+		}
+		return lang(best)
+	}
+
+	// Latin script: score by function words and characteristic letters
+	scores := make(map[string]int)
+
+	lower := strings.ToLower(query)
+	words := strings.FieldsFunc(lower, func(r rune) bool { return unicode.IsLetter(r) == false })
+	for _, word := range words {
+		for code, stopwords := range languageStopwords {
+			for _, sw := range stopwords {
+				if word == sw {
+					scores[code]++
+					break
+				}
+			}
+		}
+	}
+
+	for _, r := range lower {
+		if code, ok := languageLetters[r]; ok == true {
+			scores[code] += 2
+		}
+	}
+
+	best = bestLanguage(latinLanguages, scores)
+	if best != "" {
+		return lang(best)
+	}
+
+	// break a tie on word endings, between the tied languages only
+	var tied []string
+	top := 0
+	for _, code := range latinLanguages {
+		if scores[code] > top {
+			top = scores[code]
+		}
+	}
+	for _, code := range latinLanguages {
+		if top > 0 && scores[code] == top {
+			tied = append(tied, code)
+		}
+	}
+
+	endings := make(map[string]int)
+	for _, code := range tied {
+		for _, word := range words {
+			for _, suffix := range languageSuffixes[code] {
+				if len(word) > len(suffix) && strings.HasSuffix(word, suffix) {
+					endings[code]++
+					break
+				}
+			}
+		}
+	}
+
+	if best = bestLanguage(tied, endings); best != "" {
+		return lang(best)
+	}
+
+	return providers.QueryLanguage{}
+}
+
+// bestLanguage returns the language with the highest score, or an empty code if no
+// language scores or the highest score is tied
+func bestLanguage(codes []string, scores map[string]int) string {
+	best := ""
+	tied := false
+	for _, code := range codes {
+		switch {
+		case scores[code] > scores[best]:
+			best = code
+			tied = false
+		case scores[code] > 0 && scores[code] == scores[best]:
+			tied = true
+		}
+	}
+
+	if tied == true {
+		return ""
+	}
+
+	return best
+}
+
+// isEnglish reports whether a query is known or assumed to be in English
+func isEnglish(lang providers.QueryLanguage) bool {
+	return lang.Code == "" || lang.Code == "en"
+}
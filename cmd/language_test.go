@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		// function words
+		{"history of the civil war", "en"},
+		{"geschichte der deutschen sprache", "de"},
+		{"storia della lingua italiana", "it"},
+		{"história do brasil", "pt"},
+		{"los niños y la guerra", "es"},
+		{"les misérables et le bossu", "fr"},
+
+		// Spanish and French share "de" and "la", so word endings decide
+		{"historia de la guerra civil", "es"},
+		{"la guerre de cent ans", "fr"},
+		{"la revolución de la independencia", "es"},
+		{"la littérature de la renaissance", "fr"},
+
+		// characteristic letters
+		{"straße", "de"},
+		{"año", "es"},
+
+		// other scripts
+		{"Война и мир", "ru"},
+		{"Пісні України", "uk"},
+		{"東京物語", "zh"},
+		{"ノルウェイの森", "ja"},
+		{"Ἰλιάς", "el"},
+
+		// nothing to go on
+		{"mark twain", ""},
+		{"de la", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := detectLanguage(tt.query); got.Code != tt.want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", tt.query, got.Code, tt.want)
+		}
+	}
+}
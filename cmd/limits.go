@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// SuggestionLimits overrides the configured limits of one suggestion type for a single request.
+// Zero leaves the configured value in place.
+type SuggestionLimits struct {
+	Limit      int `json:"limit"`
+	Retrieve   int `json:"retrieve"`
+	Candidates int `json:"candidates"`
+}
+
+// suggestion types that have limits, in the order they are documented
+var suggestionLimitTypes = []string{"author", "book", "image", "subject", "series"}
+
+// default per-type limits.  Images and series are not generated by the LLM, so have no
+// candidate limit; series retrieval is the number of catalog records examined for series.
+var defaultSuggestionLimits = map[string]serviceConfigLimits{
+	"author":  {Limit: 8, Retrieve: 10, Candidates: 20},
+	"book":    {Limit: 15, Retrieve: 20, Candidates: 15},
+	"image":   {Limit: 20, Retrieve: 20},
+	"subject": {Limit: 10, Retrieve: 10, Candidates: 10},
+	"series":  {Limit: 5, Retrieve: 50},
+}
+
+// default upper bounds on per-request limits
+var defaultSuggestionMaxLimits = map[string]serviceConfigLimits{
+	"author":  {Limit: 20, Retrieve: 50, Candidates: 40},
+	"book":    {Limit: 30, Retrieve: 50, Candidates: 30},
+	"image":   {Limit: 50, Retrieve: 50},
+	"subject": {Limit: 20, Retrieve: 50, Candidates: 20},
+	"series":  {Limit: 20, Retrieve: 200},
+}
+
+// forType returns the config of a suggestion type, or nil for an unknown type
+func (t *serviceConfigSuggestionTypes) forType(suggType string) *serviceConfigSuggestion {
+	switch suggType {
+	case "author":
+		return &t.Author
+	case "book":
+		return &t.Book
+	case "image":
+		return &t.Image
+	case "subject":
+		return &t.Subject
+	case "series":
+		return &t.Series
+	}
+	return nil
+}
+
+// applyLimitDefaults fills in any limits not set in config.  Bounds are raised to at
+// least the configured limits, so a configured limit is always allowed.
+func (c *serviceConfigSuggestion) applyLimitDefaults(def serviceConfigLimits, max serviceConfigLimits) {
+	if c.Limit == 0 {
+		c.Limit = def.Limit
+	}
+	if c.Retrieve == 0 {
+		c.Retrieve = def.Retrieve
+	}
+	if c.Candidates == 0 {
+		c.Candidates = def.Candidates
+	}
+
+	if c.Max.Limit == 0 {
+		c.Max.Limit = max.Limit
+	}
+	if c.Max.Retrieve == 0 {
+		c.Max.Retrieve = max.Retrieve
+	}
+	if c.Max.Candidates == 0 {
+		c.Max.Candidates = max.Candidates
+	}
+
+	if c.Max.Limit < c.Limit {
+		c.Max.Limit = c.Limit
+	}
+	if c.Max.Retrieve < c.Retrieve {
+		c.Max.Retrieve = c.Retrieve
+	}
+	if c.Max.Candidates < c.Candidates {
+		c.Max.Candidates = c.Candidates
+	}
+}
+
+func clampLimit(val int, max int) int {
+	if val < 0 {
+		return 0
+	}
+	if val > max {
+		return max
+	}
+	return val
+}
+
+// validateLimits normalizes the limit overrides of the request, reporting unknown types.
+// Out-of-range values are clamped, as thresholds are.
+func (s *SuggestionContext) validateLimits(fieldError func(field string, format string, args ...interface{})) {
+	req := &s.req
+
+	for suggType, l := range req.Limits {
+		cfg := s.svc.config.Suggestions.forType(suggType)
+		if cfg == nil {
+			fieldError("limits", "unknown suggestion type [%s]; valid types are: %s", suggType, strings.Join(suggestionLimitTypes, ", "))
+			delete(req.Limits, suggType)
+			continue
+		}
+
+		l.Limit = clampLimit(l.Limit, cfg.Max.Limit)
+		l.Retrieve = clampLimit(l.Retrieve, cfg.Max.Retrieve)
+		l.Candidates = clampLimit(l.Candidates, cfg.Max.Candidates)
+
+		req.Limits[suggType] = l
+	}
+}
+
+// limits returns the limits of a suggestion type for this request
+func (s *SuggestionContext) limits(suggType string) serviceConfigLimits {
+	cfg := s.svc.config.Suggestions.forType(suggType)
+	if cfg == nil {
+		return serviceConfigLimits{}
+	}
+
+	l := cfg.serviceConfigLimits
+
+	if override, ok := s.req.Limits[suggType]; ok == true {
+		if override.Limit > 0 {
+			l.Limit = override.Limit
+		}
+		if override.Retrieve > 0 {
+			l.Retrieve = override.Retrieve
+		}
+		if override.Candidates > 0 {
+			l.Candidates = override.Candidates
+		}
+	}
+
+	return l
+}
+
+// capSuggestions trims each suggestion type to its output limit, keeping the best ranked
+func (s *SuggestionContext) capSuggestions(res *SuggestionResponse) {
+	counts := make(map[string]int)
+
+	kept := res.Suggestions[:0]
+	for _, sugg := range res.Suggestions {
+		suggType := sugg.Type
+		if suggType == "" {
+			suggType = "author"
+		}
+
+		if counts[suggType] >= s.limits(suggType).Limit {
+			continue
+		}
+
+		counts[suggType]++
+		kept = append(kept, sugg)
+	}
+	res.Suggestions = kept
+
+	capList := func(list []Suggestion, suggType string) []Suggestion {
+		if limit := s.limits(suggType).Limit; len(list) > limit {
+			return list[:limit]
+		}
+		return list
+	}
+
+	res.Authors = capList(res.Authors, "author")
+	res.Books = capList(res.Books, "book")
+	res.Images = capList(res.Images, "image")
+	res.Subjects = capList(res.Subjects, "subject")
+	res.Series = capList(res.Series, "series")
+
+	log.Printf("[DEBUG] Final Composition: %s, total=%d", formatCounts(counts), len(res.Suggestions))
+}
+
+// formatCounts formats per-type suggestion counts for logging
+func formatCounts(counts map[string]int) string {
+	var parts []string
+	for _, t := range suggestionLimitTypes {
+		parts = append(parts, fmt.Sprintf("%s=%d", t, counts[t]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// limitsSchema returns the JSON schema for the limit overrides of a request
+func (svc *ServiceContext) limitsSchema() map[string]interface{} {
+	props := make(map[string]interface{})
+
+	for _, t := range suggestionLimitTypes {
+		cfg := svc.config.Suggestions.forType(t)
+
+		limit := func(max int, desc string) map[string]interface{} {
+			return map[string]interface{}{
+				"type":        "integer",
+				"minimum":     0,
+				"maximum":     max,
+				"description": desc + " (0 uses the configured value; out-of-range values are clamped)",
+			}
+		}
+
+		fields := map[string]interface{}{
+			"limit":    limit(cfg.Max.Limit, fmt.Sprintf("%s suggestions returned (default %d)", t, cfg.Limit)),
+			"retrieve": limit(cfg.Max.Retrieve, fmt.Sprintf("%s hits retrieved as context (default %d)", t, cfg.Retrieve)),
+		}
+		if cfg.Max.Candidates > 0 {
+			fields["candidates"] = limit(cfg.Max.Candidates, fmt.Sprintf("%s suggestions requested from the LLM (default %d)", t, cfg.Candidates))
+		}
+
+		props[t] = map[string]interface{}{
+			"type":       "object",
+			"properties": fields,
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"description":          "per-type overrides of the configured limits",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLimitConfig(t *testing.T) {
+	var cfg serviceConfigSuggestionTypes
+	if err := json.Unmarshal([]byte(`{"author": {"limit": 5}, "book": {"returned": 3, "max": {"returned": 2}}}`), &cfg); err != nil {
+		t.Fatalf("decoding config: %v", err)
+	}
+
+	for _, suggType := range suggestionLimitTypes {
+		cfg.forType(suggType).applyLimitDefaults(defaultSuggestionLimits[suggType], defaultSuggestionMaxLimits[suggType])
+	}
+
+	// the legacy author limit does not change what /suggest returns
+	if cfg.Author.LegacyLimit != 5 || cfg.Author.Limit != defaultSuggestionLimits["author"].Limit {
+		t.Errorf("author legacy limit %d, returned %d; want 5 and %d", cfg.Author.LegacyLimit, cfg.Author.Limit, defaultSuggestionLimits["author"].Limit)
+	}
+
+	// bounds are raised to the configured limits
+	if cfg.Book.Limit != 3 || cfg.Book.Max.Limit != 3 {
+		t.Errorf("book returned %d, max %d; want 3 and 3", cfg.Book.Limit, cfg.Book.Max.Limit)
+	}
+
+	if cfg.Image.Candidates != 0 || cfg.Image.Max.Candidates != 0 {
+		t.Errorf("image candidates %d, max %d; want none", cfg.Image.Candidates, cfg.Image.Max.Candidates)
+	}
+}
@@ -50,9 +50,8 @@ func main() {
 	router.GET("/version", svc.VersionHandler)
 	router.GET("/healthcheck", svc.HealthCheckHandler)
 
-	if api := router.Group("/api"); api != nil {
-		api.POST("/suggest", svc.SuggestionHandler)
-		api.POST("/suggest/authors", svc.AuthorSuggestionHandler)
+	if api := router.Group(apiPrefix); api != nil {
+		svc.registerAPIRoutes(api)
 	}
 
 	if admin := router.Group("/admin", svc.AuthenticateHandler, svc.AdminHandler); admin != nil {
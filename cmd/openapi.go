@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiPrefix = "/api"
+
+// apiRoute describes a single API endpoint.  The same table is used to register the
+// routes with gin and to generate the OpenAPI document, so the two cannot drift apart.
+type apiRoute struct {
+	Method        string
+	Path          string // relative to apiPrefix, in gin syntax
+	Summary       string
+	Description   string
+	Request       interface{} // zero value of the request body type, or nil
+	Response      interface{} // zero value of the success response body type
+	ErrorStatuses []int       // statuses that return a SuggestionResponse carrying errors
+	Handler       gin.HandlerFunc
+}
+
+var suggestionErrorStatuses = []int{
+	http.StatusBadRequest,
+	http.StatusForbidden,
+	http.StatusUnprocessableEntity,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// apiRoutes returns the full set of API endpoints served under apiPrefix
+func (svc *ServiceContext) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			Method:        http.MethodPost,
+			Path:          "/suggest",
+			Summary:       "Suggest alternate searches",
+			Description:   "Legacy contract used by the current Virgo4 client; new clients should use /v1/suggest.",
+			Request:       SuggestionRequest{},
+			Response:      SuggestionResponse{},
+			ErrorStatuses: suggestionErrorStatuses,
+			Handler:       svc.SuggestionHandler,
+		},
+		{
+			Method:        http.MethodPost,
+			Path:          "/suggest/authors",
+			Summary:       "Suggest author searches",
+			Description:   "Legacy contract used by the current Virgo4 client; new clients should use /v1/suggest/authors.",
+			Request:       SuggestionRequest{},
+			Response:      SuggestionResponse{},
+			ErrorStatuses: suggestionErrorStatuses,
+			Handler:       svc.AuthorSuggestionHandler,
+		},
+		{
+			Method:        http.MethodPost,
+			Path:          "/suggest/batch",
+			Summary:       "Suggest alternate searches for several queries",
+			Description:   "Legacy contract; new clients should use /v1/suggest/batch.",
+			Request:       []SuggestionRequest{},
+			Response:      BatchSuggestionResponse{},
+			ErrorStatuses: []int{http.StatusBadRequest},
+			Handler:       svc.BatchSuggestionHandler,
+		},
+		{
+			Method:        http.MethodPost,
+			Path:          "/v1/suggest",
+			Summary:       "Suggest alternate searches (v1)",
+			Description:   "Gathers knowledge base context, asks the AI provider for candidates, and verifies them against the catalog. Each suggestion appears once, in ranked order, with a typed payload per kind.",
+			Request:       SuggestionRequest{},
+			Response:      V1SuggestionResponse{},
+			ErrorStatuses: suggestionErrorStatuses,
+			Handler:       svc.V1SuggestionHandler,
+		},
+		{
+			Method:        http.MethodPost,
+			Path:          "/v1/suggest/authors",
+			Summary:       "Suggest author searches (v1)",
+			Description:   "Lexical author suggestions from the Solr autocomplete core.",
+			Request:       SuggestionRequest{},
+			Response:      V1SuggestionResponse{},
+			ErrorStatuses: suggestionErrorStatuses,
+			Handler:       svc.V1AuthorSuggestionHandler,
+		},
+		{
+			Method:        http.MethodPost,
+			Path:          "/v1/suggest/batch",
+			Summary:       "Suggest alternate searches for several queries (v1)",
+			Description:   "Runs each request as /v1/suggest would, with bounded concurrency and a shared cache, and returns the results in request order.",
+			Request:       []SuggestionRequest{},
+			Response:      V1BatchSuggestionResponse{},
+			ErrorStatuses: []int{http.StatusBadRequest},
+			Handler:       svc.V1BatchSuggestionHandler,
+		},
+		{
+			Method:        http.MethodGet,
+			Path:          "/v1/authors/*facet",
+			Summary:       "Author knowledge panel (v1)",
+			Description:   "Biography, life dates, catalog record count, notable works and related authors for one author, identified by their catalog facet value. Panels are cached.",
+			Response:      AuthorPanel{},
+			ErrorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+			Handler:       svc.AuthorPanelHandler,
+		},
+		{
+			Method:        http.MethodGet,
+			Path:          "/authors/*facet",
+			Summary:       "Author knowledge panel",
+			Description:   "Same as /v1/authors/{facet}.",
+			Response:      AuthorPanel{},
+			ErrorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+			Handler:       svc.AuthorPanelHandler,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/schema/suggestion-request.json",
+			Summary:  "JSON schema for suggestion requests",
+			Response: map[string]interface{}{},
+			Handler:  svc.RequestSchemaHandler,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/openapi.json",
+			Summary:  "This OpenAPI document",
+			Response: map[string]interface{}{},
+			Handler:  svc.OpenAPIHandler,
+		},
+	}
+}
+
+// registerAPIRoutes adds every endpoint in the route table to the given group
+func (svc *ServiceContext) registerAPIRoutes(api *gin.RouterGroup) {
+	for _, r := range svc.apiRoutes() {
+		api.Handle(r.Method, r.Path, r.Handler)
+	}
+}
+
+// reGinParam matches gin path parameters (:name or *name)
+var reGinParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+func openAPIPath(ginPath string) string {
+	return apiPrefix + reGinParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+// schemaGenerator builds OpenAPI schemas from Go types, collecting named structs as components
+type schemaGenerator struct {
+	components map[string]interface{}
+	overrides  map[string]map[string]interface{}
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.components[name]; ok == false {
+			// reserve the name first so recursive types terminate
+			g.components[name] = map[string]interface{}{}
+			schema := g.structSchema(t)
+			if override, ok := g.overrides[name]; ok {
+				schema = override
+			}
+			g.components[name] = schema
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem())}
+
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+
+	// interfaces and anything else are left unconstrained
+	return map[string]interface{}{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	var required []string
+
+	for _, f := range jsonFields(t) {
+		schema := g.schemaFor(f.field.Type)
+		if f.omitempty == false {
+			required = append(required, f.name)
+
+			// encoding/json writes nil slices, maps and pointers as null
+			switch f.field.Type.Kind() {
+			case reflect.Slice, reflect.Map, reflect.Ptr:
+				schema = nullableSchema(schema)
+			}
+		}
+		props[f.name] = schema
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// nullableSchema allows null as well as the given schema.  OpenAPI 3.0 ignores keywords
+// beside $ref, so references are wrapped in allOf.
+func nullableSchema(schema map[string]interface{}) map[string]interface{} {
+	if _, ok := schema["$ref"]; ok == true {
+		return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+	}
+
+	nullable := make(map[string]interface{})
+	for k, v := range schema {
+		nullable[k] = v
+	}
+	nullable["nullable"] = true
+
+	return nullable
+}
+
+type jsonField struct {
+	name      string
+	omitempty bool
+	field     reflect.StructField
+}
+
+// jsonFields returns the fields of a struct as encoding/json would serialize them
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() == false {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = f.Name
+		}
+
+		omitempty := false
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitempty = true
+			}
+		}
+
+		fields = append(fields, jsonField{name: name, omitempty: omitempty, field: f})
+	}
+
+	return fields
+}
+
+// openAPIDocument generates the OpenAPI 3 document for the route table
+func (svc *ServiceContext) openAPIDocument() map[string]interface{} {
+	// OpenAPI 3.0 schema objects do not allow $schema
+	reqSchema := svc.suggestionRequestSchema()
+	delete(reqSchema, "$schema")
+
+	g := schemaGenerator{
+		components: make(map[string]interface{}),
+		overrides:  map[string]map[string]interface{}{"SuggestionRequest": reqSchema},
+	}
+
+	paths := make(map[string]interface{})
+
+	for _, r := range svc.apiRoutes() {
+		op := map[string]interface{}{"summary": r.Summary}
+		if r.Description != "" {
+			op["description"] = r.Description
+		}
+
+		var params []interface{}
+		for _, m := range reGinParam.FindAllStringSubmatch(r.Path, -1) {
+			params = append(params, map[string]interface{}{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if r.Request != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": g.schemaFor(reflect.TypeOf(r.Request))},
+				},
+			}
+		}
+
+		responses := map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "success",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": g.schemaFor(reflect.TypeOf(r.Response))},
+				},
+			},
+		}
+		for _, status := range r.ErrorStatuses {
+			responses[fmt.Sprintf("%d", status)] = map[string]interface{}{
+				"description": http.StatusText(status) + "; see the errors list",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": g.schemaFor(reflect.TypeOf(r.Response))},
+				},
+			}
+		}
+		op["responses"] = responses
+
+		path := openAPIPath(r.Path)
+		item, ok := paths[path].(map[string]interface{})
+		if ok == false {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(r.Method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Virgo4 search suggestor service",
+			"description": "Alternate search suggestions for Virgo4 keyword searches.",
+			"version":     GitCommit,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": g.components},
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document for this service
+func (svc *ServiceContext) OpenAPIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, svc.openAPIDocument())
+}
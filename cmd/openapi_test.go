@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// contractProvider is an AI provider and knowledge base that answers every request
+// with the same author, book and subject
+type contractProvider struct{}
+
+func (p contractProvider) suggestions(suggType string, name string) *providers.AIResponse {
+	return &providers.AIResponse{Suggestions: []providers.AIResponseSuggestion{{Name: name, Type: suggType, Reason: "contract test"}}}
+}
+
+func (p contractProvider) GetAuthorSuggestions(query string, customPrompt string, suggContext providers.SuggestionContextData, debug bool) (*providers.AIResponse, error) {
+	return p.suggestions("author", "Twain, Mark, 1835-1910"), nil
+}
+
+func (p contractProvider) GetBookSuggestions(query string, customPrompt string, suggContext providers.SuggestionContextData, debug bool) (*providers.AIResponse, error) {
+	return p.suggestions("book", "Roughing it"), nil
+}
+
+func (p contractProvider) GetSubjectSuggestions(query string, customPrompt string, suggContext providers.SuggestionContextData, debug bool) (*providers.AIResponse, error) {
+	return p.suggestions("subject", "Twain, Mark, 1835-1910"), nil
+}
+
+func (p contractProvider) GetDidYouMean(query string, language providers.QueryLanguage, debug bool) (*providers.AIDymResponse, error) {
+	return &providers.AIDymResponse{DidYouMean: "mark twain"}, nil
+}
+
+func (p contractProvider) Name() string     { return "contract" }
+func (p contractProvider) GetModel() string { return "contract" }
+
+func (p contractProvider) Retrieve(query string, limit int, threshold float64) ([]providers.AuthorHit, error) {
+	return []providers.AuthorHit{{Name: "Twain, Mark, 1835-1910", FacetLabel: "Twain, Mark, 1835-1910", Bio: "American writer", Score: 0.9}}, nil
+}
+
+func (p contractProvider) RetrieveImages(query string, limit int, threshold float64) ([]providers.ImageHit, error) {
+	return []providers.ImageHit{{ID: "img1", IIIFID: "iiif1", Title: "Mark Twain", Score: 0.9}}, nil
+}
+
+func (p contractProvider) RetrieveBooks(query string, limit int, threshold float64) ([]providers.BookHit, error) {
+	return []providers.BookHit{{ID: "u1", Title: "Roughing it", Authors: []string{"Twain, Mark, 1835-1910"}, Score: 0.9}}, nil
+}
+
+// contractSolr answers every Solr request with one document carrying the fields the
+// handlers read, so each route can produce a full response without a real catalog.
+// Requests mentioning the misspelling "twian" find nothing.
+func contractSolr(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body)+r.URL.RawQuery, "twian") {
+			fmt.Fprint(w, `{"responseHeader": {"status": 0}, "response": {"numFound": 0, "docs": []}}`)
+			return
+		}
+
+		fmt.Fprint(w, `{
+			"responseHeader": {"status": 0},
+			"response": {"numFound": 1, "maxScore": 1.0, "docs": [
+				{"id": "u1", "phrase": "Twain, Mark, 1835-1910", "type": "author", "count": 3, "score": 1.0,
+				 "title_a": ["Roughing it"], "author_a": ["Twain, Mark, 1835-1910"]}
+			]},
+			"facet_counts": {"facet_fields": {}}
+		}`)
+	}))
+}
+
+// contractService builds the service against a fake Solr and the contract provider,
+// with no background dictionary or graph builds
+func contractService(t *testing.T) *ServiceContext {
+	t.Helper()
+
+	solr := contractSolr(t)
+	t.Cleanup(solr.Close)
+
+	for _, env := range getSortedJSONEnvVars() {
+		t.Setenv(env, "")
+	}
+	t.Setenv(envPrefix+"_SOLR_HOST", solr.URL)
+	t.Setenv(envPrefix+"_LOCAL_INDEX", "")
+	t.Setenv(envPrefix+"_JSON_1", `{"didyoumean": {"disabled": true}, "graph": {"disabled": true}, "ai": {"provider": "none"}}`)
+
+	svc := InitializeService(loadConfig())
+	svc.AIProvider = contractProvider{}
+	svc.Retriever = svc.AIProvider
+
+	return svc
+}
+
+// contractRequest is one request made against a route, and the status it must return
+type contractRequest struct {
+	method string
+	route  string // the gin path of the route it exercises
+	path   string
+	body   string
+	status int
+}
+
+var contractRequests = []contractRequest{
+	{http.MethodPost, "/suggest", "/suggest", `{"query": "keyword: {mark twain}", "features": ["author", "book", "subject", "images", "series", "didyoumean"], "debug": true}`, http.StatusOK},
+	{http.MethodPost, "/suggest", "/suggest", `{"query": "keyword: {mark twain}", "features": ["author", "kb-only"]}`, http.StatusOK},
+	{http.MethodPost, "/suggest", "/suggest", `{"query": "keyword: {mark twian}", "features": ["didyoumean"]}`, http.StatusOK},
+	{http.MethodPost, "/suggest", "/suggest", `{"query": "keyword: {mark twain}", "features": ["unknown"]}`, http.StatusBadRequest},
+	{http.MethodPost, "/suggest", "/suggest", `{"query": "published: {1900}", "features": ["author", "didyoumean"]}`, http.StatusUnprocessableEntity},
+	{http.MethodPost, "/suggest/authors", "/suggest/authors", `{"query": "keyword: {mark twain}"}`, http.StatusOK},
+	{http.MethodPost, "/suggest/authors", "/suggest/authors", `{"query": ""}`, http.StatusBadRequest},
+	{http.MethodPost, "/suggest/batch", "/suggest/batch", `[{"query": "keyword: {mark twain}"}, {"query": ""}]`, http.StatusOK},
+	{http.MethodPost, "/suggest/batch", "/suggest/batch", `{"query": "not a list"}`, http.StatusBadRequest},
+	{http.MethodPost, "/v1/suggest", "/v1/suggest", `{"query": "keyword: {mark twain}", "features": ["author", "book", "subject", "images", "series", "didyoumean"], "debug": true}`, http.StatusOK},
+	{http.MethodPost, "/v1/suggest", "/v1/suggest", `{"query": "keyword: {mark twain}", "features": ["author", "kb-only"]}`, http.StatusOK},
+	{http.MethodPost, "/v1/suggest", "/v1/suggest", `{"query": "keyword: {mark twian}", "features": ["didyoumean"]}`, http.StatusOK},
+	{http.MethodPost, "/v1/suggest", "/v1/suggest", `{"query": "keyword: {mark twain}", "filters": [{"facet_id": "FacetPublishedDate", "value": "[* TO *] OR x:[a TO b]"}]}`, http.StatusBadRequest},
+	{http.MethodPost, "/v1/suggest", "/v1/suggest", `{"query": "keyword: {mark twain}", "pool": "articles", "features": ["author", "kb-only"]}`, http.StatusOK},
+	{http.MethodPost, "/v1/suggest/authors", "/v1/suggest/authors", `{"query": "keyword: {mark twain}"}`, http.StatusOK},
+	{http.MethodPost, "/v1/suggest/batch", "/v1/suggest/batch", `[{"query": "keyword: {mark twain}"}]`, http.StatusOK},
+	{http.MethodPost, "/v1/suggest/batch", "/v1/suggest/batch", `[]`, http.StatusBadRequest},
+	{http.MethodGet, "/v1/authors/*facet", "/v1/authors/Twain,%20Mark,%201835-1910", "", http.StatusOK},
+	{http.MethodGet, "/authors/*facet", "/authors/Twain,%20Mark,%201835-1910", "", http.StatusOK},
+	{http.MethodGet, "/schema/suggestion-request.json", "/schema/suggestion-request.json", "", http.StatusOK},
+	{http.MethodGet, "/openapi.json", "/openapi.json", "", http.StatusOK},
+}
+
+// TestOpenAPIContract calls every documented route through the gin router and checks
+// the status and response body against the generated OpenAPI document
+func TestOpenAPIContract(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := contractService(t)
+
+	router := gin.New()
+	svc.registerAPIRoutes(router.Group(apiPrefix))
+
+	// round trip the document through JSON so it is checked as clients see it
+	var doc map[string]interface{}
+	if err := json.Unmarshal(mustJSON(t, svc.openAPIDocument()), &doc); err != nil {
+		t.Fatalf("decoding the OpenAPI document: %v", err)
+	}
+	paths := doc["paths"].(map[string]interface{})
+
+	// every registered route is documented, and every documented route is registered
+	registered := make(map[string]bool)
+	for _, r := range router.Routes() {
+		path := reGinParam.ReplaceAllString(r.Path, "{$1}")
+		registered[strings.ToLower(r.Method)+" "+path] = true
+
+		if item, ok := paths[path].(map[string]interface{}); ok == false || item[strings.ToLower(r.Method)] == nil {
+			t.Errorf("route %s %s is not documented", r.Method, r.Path)
+		}
+	}
+	for path, item := range paths {
+		for method := range item.(map[string]interface{}) {
+			if registered[method+" "+path] == false {
+				t.Errorf("documented %s %s is not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	exercised := make(map[string]bool)
+
+	for _, cr := range contractRequests {
+		name := fmt.Sprintf("%s %s %d", cr.method, cr.path, cr.status)
+		exercised[strings.ToLower(cr.method)+" "+openAPIPath(cr.route)] = true
+
+		t.Run(name, func(t *testing.T) {
+			var body io.Reader
+			if cr.body != "" {
+				body = strings.NewReader(cr.body)
+			}
+
+			req := httptest.NewRequest(cr.method, apiPrefix+cr.path, body)
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != cr.status {
+				t.Fatalf("status %d, want %d; body: %s", w.Code, cr.status, w.Body.String())
+			}
+
+			op, ok := paths[openAPIPath(cr.route)].(map[string]interface{})[strings.ToLower(cr.method)].(map[string]interface{})
+			if ok == false {
+				t.Fatalf("route %s %s is not documented", cr.method, cr.route)
+			}
+
+			resp, ok := op["responses"].(map[string]interface{})[fmt.Sprintf("%d", w.Code)].(map[string]interface{})
+			if ok == false {
+				t.Fatalf("status %d is not documented for %s %s", w.Code, cr.method, cr.route)
+			}
+
+			schema := resp["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+
+			var val interface{}
+			dec := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+			dec.UseNumber()
+			if err := dec.Decode(&val); err != nil {
+				t.Fatalf("response is not JSON: %v; body: %s", err, w.Body.String())
+			}
+
+			for _, problem := range validateSchema(doc, schema, val, "$") {
+				t.Error(problem)
+			}
+		})
+	}
+
+	for path, item := range paths {
+		for method := range item.(map[string]interface{}) {
+			if exercised[method+" "+path] == false {
+				t.Errorf("documented %s %s has no contract request", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+// TestRequestSchemaFields checks that the hand-written request schema, which carries the
+// validation limits, describes exactly the fields of SuggestionRequest
+func TestRequestSchemaFields(t *testing.T) {
+	svc := contractService(t)
+
+	props := svc.suggestionRequestSchema()["properties"].(map[string]interface{})
+
+	fields := make(map[string]bool)
+	for _, f := range jsonFields(reflect.TypeOf(SuggestionRequest{})) {
+		fields[f.name] = true
+		if _, ok := props[f.name]; ok == false {
+			t.Errorf("SuggestionRequest field %s is missing from the request schema", f.name)
+		}
+	}
+
+	for name := range props {
+		if fields[name] == false {
+			t.Errorf("request schema property %s is not a SuggestionRequest field", name)
+		}
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("encoding: %v", err)
+	}
+
+	return buf
+}
+
+// validateSchema checks a decoded JSON value against the subset of OpenAPI schema
+// objects the generator produces, returning a description of each mismatch.  Objects
+// without additionalProperties are closed: a property the schema does not list is a
+// mismatch, so responses cannot carry fields the document does not describe.
+func validateSchema(doc map[string]interface{}, schema map[string]interface{}, val interface{}, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok == true {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		target, ok := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})[name].(map[string]interface{})
+		if ok == false {
+			return []string{fmt.Sprintf("%s: unresolved reference %s", at, ref)}
+		}
+		return validateSchema(doc, target, val, at)
+	}
+
+	if val == nil {
+		if schema["nullable"] == true || len(schema) == 0 {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: null, but the schema is not nullable", at)}
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok == true {
+		var problems []string
+		for _, sub := range all {
+			problems = append(problems, validateSchema(doc, sub.(map[string]interface{}), val, at)...)
+		}
+		return problems
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := val.(map[string]interface{})
+		if ok == false {
+			return []string{fmt.Sprintf("%s: %T, want object", at, val)}
+		}
+
+		var problems []string
+
+		props, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; ok == false {
+				problems = append(problems, fmt.Sprintf("%s: missing required property %s", at, name))
+			}
+		}
+
+		extra, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, v := range obj {
+			if prop, ok := props[name].(map[string]interface{}); ok == true {
+				problems = append(problems, validateSchema(doc, prop, v, at+"."+name)...)
+			} else if extra != nil {
+				problems = append(problems, validateSchema(doc, extra, v, at+"."+name)...)
+			} else if props != nil {
+				problems = append(problems, fmt.Sprintf("%s: property %s is not in the schema", at, name))
+			}
+		}
+
+		return problems
+
+	case "array":
+		list, ok := val.([]interface{})
+		if ok == false {
+			return []string{fmt.Sprintf("%s: %T, want array", at, val)}
+		}
+
+		var problems []string
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range list {
+			problems = append(problems, validateSchema(doc, items, v, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+
+		return problems
+
+	case "string":
+		if _, ok := val.(string); ok == false {
+			return []string{fmt.Sprintf("%s: %T, want string", at, val)}
+		}
+
+	case "boolean":
+		if _, ok := val.(bool); ok == false {
+			return []string{fmt.Sprintf("%s: %T, want boolean", at, val)}
+		}
+
+	case "integer", "number":
+		num, ok := val.(json.Number)
+		if ok == false {
+			return []string{fmt.Sprintf("%s: %T, want %s", at, val, schema["type"])}
+		}
+		f, err := num.Float64()
+		if err != nil || (schema["type"] == "integer" && f != math.Trunc(f)) {
+			return []string{fmt.Sprintf("%s: %s, want %s", at, num, schema["type"])}
+		}
+	}
+
+	return nil
+}
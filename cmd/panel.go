@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uvalib/virgo4-suggestor-ws/names"
+)
+
+// author panel defaults; all may be set in config
+const (
+	defaultPanelCacheMinutes = 60
+	defaultPanelWorks        = 10
+	defaultPanelRelated      = 8
+	panelWorkRecords         = 100 // catalog records examined for the catalog IDs of notable works
+)
+
+// errPanelIncomplete marks a panel built with warnings, which is returned but not cached
+var errPanelIncomplete = errors.New("author panel is incomplete")
+
+// AuthorPanel is a knowledge panel for one author, identified by their catalog facet value
+type AuthorPanel struct {
+	Facet          string            `json:"facet"`
+	Name           string            `json:"name"`            // display form, e.g. "Mark Twain"
+	Dates          string            `json:"dates,omitempty"` // life dates as given in the facet
+	Born           int               `json:"born,omitempty"`
+	Died           int               `json:"died,omitempty"`
+	Bio            string            `json:"bio,omitempty"`
+	Count          int               `json:"count"` // catalog records
+	NotableWorks   []AuthorWork      `json:"notable_works"`
+	RelatedAuthors []RelatedAuthor   `json:"related_authors"`
+	Warnings       []SuggestionError `json:"warnings,omitempty"`
+	Errors         []SuggestionError `json:"errors,omitempty"`
+}
+
+// AuthorWork is a title by the author, linked to one of its catalog records
+type AuthorWork struct {
+	Title     string `json:"title"`
+	CatalogID string `json:"catalog_id"`
+	Count     int    `json:"count"` // catalog records by the author with this title
+}
+
+// RelatedAuthor is another author a panel links to
+type RelatedAuthor struct {
+	Facet  string `json:"facet"`
+	Count  int    `json:"count"`            // catalog records
+	Source string `json:"source"`           // graph: linked in the catalog; kb: similar knowledge base biography
+	Reason string `json:"reason,omitempty"` // for graph links, how they are linked
+}
+
+// titleKey reduces a title to a form in which catalog display titles and facet values agree
+func titleKey(title string) string {
+	return strings.Join(names.Tokens(title, false), " ")
+}
+
+// authorPanel returns the panel for an author facet, from the cache if possible.  Panels
+// with problems are returned to every request waiting on them, but are not cached.
+func (s *SuggestionContext) authorPanel(facet string) *AuthorPanel {
+	val, _ := s.svc.panels.getOrCompute(facet, func() (interface{}, error) {
+		panel := s.buildAuthorPanel(facet)
+		if len(panel.Errors) > 0 || len(panel.Warnings) > 0 {
+			return panel, errPanelIncomplete
+		}
+		return panel, nil
+	})
+
+	return val.(*AuthorPanel)
+}
+
+// buildAuthorPanel gathers the parts of an author panel concurrently: the catalog count and
+// notable works from Solr, related authors from the author graph, and the biography and
+// more related authors from the knowledge base
+func (s *SuggestionContext) buildAuthorPanel(facet string) *AuthorPanel {
+	start := time.Now()
+
+	panel := &AuthorPanel{
+		Facet:          facet,
+		Name:           names.Display(facet),
+		NotableWorks:   []AuthorWork{},
+		RelatedAuthors: []RelatedAuthor{},
+	}
+	panel.Dates, panel.Born, panel.Died = names.LifeDates(facet)
+
+	var graphRelated, kbRelated []RelatedAuthor
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+
+	go func() {
+		defer wg.Done()
+		counts, err := s.GetAuthorResourceCounts([]string{facet})
+		if err != nil {
+			log.Printf("[PANEL] count failed: %s", err.Error())
+			s.addWarning("count", err)
+			return
+		}
+		panel.Count = counts[facet]
+	}()
+
+	go func() {
+		defer wg.Done()
+		works, err := s.notableWorks(facet)
+		if err != nil {
+			log.Printf("[PANEL] notable works failed: %s", err.Error())
+			s.addWarning("works", err)
+			return
+		}
+		panel.NotableWorks = works
+	}()
+
+	go func() {
+		defer wg.Done()
+		// as with the graph, a service without a knowledge base has no biographies to
+		// offer; that is how it was set up, not a failure, so the panel is still complete
+		if s.svc.Retriever == nil {
+			return
+		}
+		bio, related, err := s.authorBioAndRelated(facet, panel.Name)
+		if err != nil {
+			log.Printf("[PANEL] knowledge base failed: %s", err.Error())
+			s.addWarning("kb", err)
+			return
+		}
+		panel.Bio = bio
+		kbRelated = related
+	}()
+
+	go func() {
+		defer wg.Done()
+		related, err := s.graphRelated(facet)
+		if err != nil {
+			log.Printf("[PANEL] graph related authors failed: %s", err.Error())
+			s.addWarning("graph", err)
+			return
+		}
+		graphRelated = related
+	}()
+
+	wg.Wait()
+
+	// catalog links first, as the firmer evidence, then similar biographies
+	seen := map[string]bool{s.personKey(facet): true}
+	for _, r := range append(graphRelated, kbRelated...) {
+		key := s.personKey(r.Facet)
+		if seen[key] == true || len(panel.RelatedAuthors) >= s.svc.config.AuthorPanel.Related {
+			continue
+		}
+		seen[key] = true
+		panel.RelatedAuthors = append(panel.RelatedAuthors, r)
+	}
+
+	s.issuesMu.Lock()
+	issues := append([]SuggestionError{}, s.issues...)
+	s.issuesMu.Unlock()
+
+	switch {
+	case len(issues) > 0 && panel.Count == 0 && panel.Bio == "":
+		panel.Errors = issues
+	case len(issues) > 0:
+		panel.Warnings = issues
+	case panel.Count == 0 && panel.Bio == "":
+		panel.Errors = []SuggestionError{{Code: errCodeNotFound, Source: "facet", Message: fmt.Sprintf("no author found for facet [%s]", facet)}}
+	}
+
+	log.Printf("[PANEL] '%s': %d records, %d works, %d related, bio=%v (took %v)", facet, panel.Count, len(panel.NotableWorks), len(panel.RelatedAuthors), panel.Bio != "", time.Since(start))
+
+	return panel
+}
+
+// notableWorks returns the titles the author has the most catalog records for, each linked
+// to one of those records
+func (s *SuggestionContext) notableWorks(facet string) ([]AuthorWork, error) {
+	cfg := s.svc.config.AuthorPanel
+	field := s.svc.config.Suggestions.Book.CountField
+
+	solrReq := SolrRequest{Core: s.svc.config.Solr.CatalogCore}
+	solrReq.json.Params = SolrRequestParams{
+		Q:          "*:*",
+		Rows:       panelWorkRecords,
+		Fl:         []string{"id", "title_a", "title_display"},
+		Fq:         []string{fmt.Sprintf("%s:%s", s.svc.config.Filters.ViewFields["author"], quoteSolrValue(facet))},
+		Facet:      true,
+		FacetField: []string{field},
+		FacetLimit: cfg.Works * 2,
+		FacetMin:   1,
+	}
+
+	solrRes, err := s.SolrQuery(&solrReq)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string)
+	for _, doc := range solrRes.Response.Docs {
+		key := titleKey(doc.displayTitle())
+		if _, ok := ids[key]; ok == false && doc.ID != "" {
+			ids[key] = doc.ID
+		}
+	}
+
+	works := []AuthorWork{}
+
+	// facet values come back as a flat [value, count, value, count, ...] list, most records first
+	values := solrRes.FacetCounts.FacetFields[field]
+	for i := 0; i+1 < len(values) && len(works) < cfg.Works; i += 2 {
+		title, _ := values[i].(string)
+		count, _ := values[i+1].(float64)
+
+		// titles with no record among those examined cannot be linked
+		id, ok := ids[titleKey(title)]
+		if ok == false {
+			continue
+		}
+
+		works = append(works, AuthorWork{Title: title, CatalogID: id, Count: int(count)})
+	}
+
+	return works, nil
+}
+
+// authorBioAndRelated finds the author's biography in the knowledge base, then the authors
+// whose biographies are most similar to it.  Without a biography, the other authors found
+// for the name are used instead.  Related authors must have catalog records.
+func (s *SuggestionContext) authorBioAndRelated(facet string, name string) (string, []RelatedAuthor, error) {
+	cfg := s.svc.config.AuthorPanel
+	self := s.personKey(facet)
+
+	// extra hits allow for the author and variants of them
+	hits, err := s.retrieveAuthors(name, cfg.Related+5, 0)
+	if err != nil {
+		return "", nil, err
+	}
+
+	bio := ""
+	for _, hit := range hits {
+		if hit.Bio != "" && (strings.EqualFold(hit.FacetLabel, facet) || s.personKey(hit.FacetLabel) == self) {
+			bio = hit.Bio
+			break
+		}
+	}
+
+	if bio != "" {
+		if hits, err = s.retrieveAuthors(bio, cfg.Related+5, 0); err != nil {
+			return bio, nil, err
+		}
+	}
+
+	var facets []string
+	seen := map[string]bool{self: true}
+	for _, hit := range hits {
+		key := s.personKey(hit.FacetLabel)
+		if hit.FacetLabel == "" || seen[key] == true {
+			continue
+		}
+		seen[key] = true
+		facets = append(facets, hit.FacetLabel)
+	}
+
+	counts, err := s.GetAuthorResourceCounts(facets)
+	if err != nil {
+		return bio, nil, err
+	}
+
+	related := []RelatedAuthor{}
+	for _, f := range facets {
+		if counts[f] == 0 || len(related) >= cfg.Related {
+			continue
+		}
+		related = append(related, RelatedAuthor{Facet: f, Count: counts[f], Source: "kb"})
+	}
+
+	return bio, related, nil
+}
+
+// graphRelated returns the author's neighbours in the author graph that have catalog records
+func (s *SuggestionContext) graphRelated(facet string) ([]RelatedAuthor, error) {
+	g := s.svc.graph()
+	if g == nil {
+		return nil, nil
+	}
+
+	edges := g.neighbours[facet]
+	if len(edges) == 0 {
+		return nil, nil
+	}
+
+	var facets []string
+	for _, e := range edges {
+		facets = append(facets, e.facet)
+	}
+
+	counts, err := s.GetAuthorResourceCounts(facets)
+	if err != nil {
+		return nil, err
+	}
+
+	var related []RelatedAuthor
+	for _, e := range edges {
+		if counts[e.facet] > 0 {
+			related = append(related, RelatedAuthor{Facet: e.facet, Count: counts[e.facet], Source: "graph", Reason: e.reason(facet)})
+		}
+	}
+
+	return related, nil
+}
+
+// AuthorPanelHandler serves the knowledge panel for the author facet in the path
+func (svc *ServiceContext) AuthorPanelHandler(c *gin.Context) {
+	facet := strings.TrimSpace(strings.TrimPrefix(c.Param("facet"), "/"))
+	if facet == "" {
+		c.JSON(http.StatusBadRequest, &AuthorPanel{
+			NotableWorks:   []AuthorWork{},
+			RelatedAuthors: []RelatedAuthor{},
+			Errors:         []SuggestionError{{Code: errCodeInvalidRequest, Field: "facet", Message: "an author facet is required"}},
+		})
+		return
+	}
+
+	panel := InitializeSuggestion(svc, c).authorPanel(facet)
+
+	status := http.StatusOK
+	if len(panel.Errors) > 0 {
+		status = statusForErrors(panel.Errors)
+	}
+
+	c.JSON(status, panel)
+}
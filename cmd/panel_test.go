@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// panelSolr knows of one author, Mark Twain, with three records and no works
+func panelSolr(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req SolrRequestJSON
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if strings.Contains(req.Params.Q, "Twain, Mark, 1835-1910") {
+			fmt.Fprint(w, `{"responseHeader": {"status": 0}, "response": {"numFound": 1, "docs": [{"phrase": "Twain, Mark, 1835-1910", "count": 3}]}}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"responseHeader": {"status": 0}, "response": {"numFound": 0, "docs": []}, "facet_counts": {"facet_fields": {}}}`)
+	}))
+}
+
+// TestAuthorPanelWithoutKnowledgeBase checks that a service with no knowledge base still
+// builds and caches complete panels, and reports unknown authors as not found
+func TestAuthorPanelWithoutKnowledgeBase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	svc := contractService(t)
+	svc.Retriever = nil
+
+	solr := panelSolr(t)
+	t.Cleanup(solr.Close)
+	svc.solr.service.host = solr.URL
+
+	router := gin.New()
+	svc.registerAPIRoutes(router.Group(apiPrefix))
+
+	get := func(facet string) (int, AuthorPanel) {
+		req := httptest.NewRequest(http.MethodGet, apiPrefix+"/authors/"+facet, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var panel AuthorPanel
+		if err := json.Unmarshal(w.Body.Bytes(), &panel); err != nil {
+			t.Fatalf("decoding panel for %s: %v", facet, err)
+		}
+		return w.Code, panel
+	}
+
+	status, panel := get("Twain,%20Mark,%201835-1910")
+	if status != http.StatusOK || panel.Count != 3 || len(panel.Warnings) != 0 || len(panel.Errors) != 0 {
+		t.Errorf("known author: status %d, panel %+v; want %d with 3 records and no problems", status, panel, http.StatusOK)
+	}
+	if _, ok := svc.panels.entries["Twain, Mark, 1835-1910"]; ok == false {
+		t.Errorf("known author: panel was not cached")
+	}
+
+	status, panel = get("Nobody,%20A.")
+	if status != http.StatusNotFound || len(panel.Errors) != 1 || panel.Errors[0].Code != errCodeNotFound {
+		t.Errorf("unknown author: status %d, errors %+v; want %d not_found", status, panel.Errors, http.StatusNotFound)
+	}
+}
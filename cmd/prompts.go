@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// errPromptForbidden is returned when a non-admin caller supplies a free-form prompt
+var errPromptForbidden = errors.New("free-form aiPrompt requires an admin token")
+
+// promptVariant is a registered user prompt that callers may select by ID.
+// Templates may reference $QUERY, $FIELDS, $LANGUAGE, $LIMIT and $SUGGESTIONS, which the
+// AI provider substitutes with the user query, its fielded clauses, a note on its language
+// (empty for English), the number of suggestions wanted and the gathered background research
+// (for authors, the direct hits followed by any catalog-linked authors).
+// An empty template for a suggestion type falls back to the provider default.
+type promptVariant struct {
+	ID          string
+	Description string
+	Author      string
+	Book        string
+	Subject     string
+}
+
+// builtinPromptVariants are always available, and may be overridden by config
+var builtinPromptVariants = []promptVariant{
+	{
+		ID:          "exact",
+		Description: "only suggest authors whose names closely match the query",
+		Author: `USER QUERY: "$QUERY"
+
+=== BACKGROUND RESEARCH ===
+$SUGGESTIONS
+===========================
+
+INSTRUCTION: The query is most likely an author name. Suggest ONLY the canonical author(s) whose names match the query, correcting spelling and name order as needed. Do not suggest topically related authors. Output MUST be ONLY the raw JSON object. START RESPONSE WITH '{' AND NOTHING ELSE.
+`,
+	},
+	{
+		ID:          "topical",
+		Description: "favor authors and titles associated with the query topic over name matches",
+		Author: `USER QUERY: "$QUERY"
+
+=== BACKGROUND RESEARCH ===
+$SUGGESTIONS
+===========================
+
+INSTRUCTION: Treat the query as a research topic. Suggest up to $LIMIT authors who are recognized authorities on this topic, prioritizing those found in the Background Research, in descending order of confidence. Output MUST be ONLY the raw JSON object. START RESPONSE WITH '{' AND NOTHING ELSE.
+`,
+		Book: `USER QUERY: "$QUERY"
+
+=== BACKGROUND RESEARCH ===
+$SUGGESTIONS
+===========================
+
+INSTRUCTION: Treat the query as a research topic. Suggest up to $LIMIT foundational and widely cited BOOK titles on this topic, prioritizing those found in the Background Research. Return ONLY JSON.
+`,
+		Subject: `USER QUERY: "$QUERY"
+
+=== BACKGROUND RESEARCH ===
+$SUGGESTIONS
+===========================
+
+INSTRUCTION: Treat the query as a research topic. Suggest up to $LIMIT broader and narrower SUBJECT HEADINGS a researcher would use to explore it, prioritizing those found in the Background Research. Return ONLY JSON.
+`,
+	},
+}
+
+// loadPromptVariants builds the prompt registry from the builtin variants and any configured ones
+func loadPromptVariants(cfg []serviceConfigPrompt) map[string]promptVariant {
+	prompts := make(map[string]promptVariant)
+
+	for _, p := range builtinPromptVariants {
+		prompts[p.ID] = p
+	}
+
+	for _, p := range cfg {
+		if p.ID == "" {
+			log.Printf("[PROMPTS] skipping configured prompt with no id")
+			continue
+		}
+
+		prompts[p.ID] = promptVariant{ID: p.ID, Description: p.Description, Author: p.Author, Book: p.Book, Subject: p.Subject}
+	}
+
+	var ids []string
+	for id := range prompts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	log.Printf("[PROMPTS] registered prompt variants: %v", ids)
+
+	return prompts
+}
+
+// resolvePrompts determines the author, book and subject user prompts for this request.
+// Named variants are available to everyone; free-form prompts are only honored
+// for admin-authenticated requests, and every such use is audit-logged.
+func (s *SuggestionContext) resolvePrompts() error {
+	if s.req.PromptID != "" && s.req.AIPrompt != "" {
+		return errors.New("aiPrompt and promptId are mutually exclusive")
+	}
+
+	if s.req.PromptID != "" {
+		prompt, ok := s.svc.prompts[s.req.PromptID]
+		if ok == false {
+			return fmt.Errorf("unknown promptId: [%s]", s.req.PromptID)
+		}
+
+		log.Printf("[PROMPTS] using prompt variant [%s]", prompt.ID)
+
+		s.authorPrompt = prompt.Author
+		s.bookPrompt = prompt.Book
+		s.subjectPrompt = prompt.Subject
+
+		return nil
+	}
+
+	if s.req.AIPrompt != "" {
+		if s.isAdmin() == false {
+			log.Printf("[AUDIT] rejected free-form aiPrompt from non-admin caller: user=[%s] query=[%s]", s.userID(), s.req.Query)
+			return errPromptForbidden
+		}
+
+		log.Printf("[AUDIT] free-form aiPrompt accepted: user=[%s] query=[%s] prompt=[%s]", s.userID(), s.req.Query, s.req.AIPrompt)
+
+		s.authorPrompt = s.req.AIPrompt
+		s.bookPrompt = s.req.AIPrompt
+		s.subjectPrompt = s.req.AIPrompt
+	}
+
+	return nil
+}
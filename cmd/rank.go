@@ -0,0 +1,264 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ranking methods
+const (
+	rankMethodWeighted = "weighted" // weighted sum of normalized signals
+	rankMethodRRF      = "rrf"      // weighted reciprocal rank fusion
+)
+
+// ranking defaults; all may be set in config
+const (
+	defaultRRFK        = 60
+	popularityScale    = 4.0   // log10 of the record count treated as fully popular (10,000 records)
+	rankScorePrecision = 10000 // fused scores are rounded to 4 decimal places
+)
+
+var defaultSourceWeights = map[string]float64{
+	"hybrid": 1.0,
+	"kb":     1.0,
+	"graph":  0.9,
+	"solr":   0.8,
+	"llm":    0.5,
+}
+
+// rankSignals are the inputs to ranking that are not otherwise part of a suggestion
+type rankSignals struct {
+	llmRank    int     // 1-based position in the LLM response; 0 if the LLM did not suggest it
+	similarity float64 // closeness of a repaired suggestion to its catalog form; 0 if never repaired
+}
+
+// rankSignal names, in the order their values are returned by signals()
+const (
+	signalRetrieval = iota
+	signalLLM
+	signalSimilarity
+	signalPopularity
+	signalSource
+	signalCount
+)
+
+// sourcePriority breaks ties between equally ranked suggestions
+var sourcePriority = map[string]int{
+	"hybrid": 0,
+	"kb":     1,
+	"graph":  2,
+	"solr":   3,
+	"llm":    4,
+}
+
+// rankWeights returns the configured weight of each signal
+func (svc *ServiceContext) rankWeights() [signalCount]float64 {
+	cfg := svc.config.Ranking
+	return [signalCount]float64{
+		signalRetrieval:  cfg.RetrievalWeight,
+		signalLLM:        cfg.LLMWeight,
+		signalSimilarity: cfg.SimilarityWeight,
+		signalPopularity: cfg.PopularityWeight,
+		signalSource:     cfg.SourceWeight,
+	}
+}
+
+// signals normalizes the ranking inputs of a suggestion to [0, 1].  Solr scores are
+// not bounded, so they are scaled by the best Solr score seen for the same type.
+func (s *SuggestionContext) signals(sugg Suggestion, maxSolrScore map[string]float64) [signalCount]float64 {
+	var sig [signalCount]float64
+
+	switch sugg.Source {
+	case "kb", "graph", "hybrid":
+		sig[signalRetrieval] = math.Max(0, math.Min(1, sugg.Score))
+	case "solr":
+		if max := maxSolrScore[sugg.Type]; max > 0 {
+			sig[signalRetrieval] = sugg.Score / max
+		}
+	}
+
+	if sugg.rank.llmRank > 0 {
+		sig[signalLLM] = 1 / float64(sugg.rank.llmRank)
+	}
+
+	// suggestions that were never repaired already matched the catalog exactly
+	sig[signalSimilarity] = 1
+	if sugg.rank.similarity > 0 {
+		sig[signalSimilarity] = sugg.rank.similarity
+	}
+
+	sig[signalPopularity] = math.Min(1, math.Log10(1+float64(sugg.Count))/popularityScale)
+
+	weight, ok := s.svc.config.Ranking.SourceWeights[sugg.Source]
+	if ok == false {
+		weight = defaultSourceWeights[sugg.Source]
+	}
+	sig[signalSource] = weight
+
+	return sig
+}
+
+// rankLess orders suggestions by score, breaking ties deterministically
+func rankLess(a, b Suggestion) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if sourcePriority[a.Source] != sourcePriority[b.Source] {
+		return sourcePriority[a.Source] < sourcePriority[b.Source]
+	}
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	if strings.ToLower(a.Value) != strings.ToLower(b.Value) {
+		return strings.ToLower(a.Value) < strings.ToLower(b.Value)
+	}
+	return a.ID < b.ID
+}
+
+// canonicalLess orders suggestions by identity alone, independent of score
+func canonicalLess(a, b Suggestion) bool {
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	if strings.ToLower(a.Value) != strings.ToLower(b.Value) {
+		return strings.ToLower(a.Value) < strings.ToLower(b.Value)
+	}
+	if a.ID != b.ID {
+		return a.ID < b.ID
+	}
+	return a.Source < b.Source
+}
+
+// fuseWeighted scores each suggestion as the weighted mean of its signals
+func fuseWeighted(sigs [][signalCount]float64, weights [signalCount]float64) []float64 {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+
+	scores := make([]float64, len(sigs))
+	if total == 0 {
+		return scores
+	}
+
+	for i, sig := range sigs {
+		for j, w := range weights {
+			scores[i] += w * sig[j]
+		}
+		scores[i] /= total
+	}
+
+	return scores
+}
+
+// fuseRRF scores each suggestion by weighted reciprocal rank fusion: for each signal,
+// suggestions are ranked by that signal, and contribute weight / (k + rank).
+// Suggestions with no value for a signal get nothing for it.  Scores are divided
+// by the best possible score, so a suggestion ranked first on every signal scores 1.
+func fuseRRF(sigs [][signalCount]float64, weights [signalCount]float64, k int) []float64 {
+	scores := make([]float64, len(sigs))
+
+	best := 0.0
+	for j, w := range weights {
+		if w == 0 {
+			continue
+		}
+		best += w / float64(k+1)
+
+		order := make([]int, len(sigs))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return sigs[order[a]][j] > sigs[order[b]][j]
+		})
+
+		for rank, i := range order {
+			if sigs[i][j] > 0 {
+				scores[i] += w / float64(k+rank+1)
+			}
+		}
+	}
+
+	if best > 0 {
+		for i := range scores {
+			scores[i] /= best
+		}
+	}
+
+	return scores
+}
+
+// rankSuggestions replaces each suggestion score with a fused score built from its
+// retrieval score, LLM rank, verification similarity, catalog count and source, and
+// sorts the combined and per-type lists by it.  Images are ranked alongside everything
+// else, but only have retrieval and source signals.
+func (s *SuggestionContext) rankSuggestions(res *SuggestionResponse) {
+	if len(res.Suggestions) == 0 {
+		return
+	}
+
+	// put the list in a canonical order first, so ties in the fused score (and the
+	// per-signal ranks used by rrf) do not depend on the order verification finished in
+	sort.SliceStable(res.Suggestions, func(i, j int) bool {
+		return canonicalLess(res.Suggestions[i], res.Suggestions[j])
+	})
+
+	maxSolrScore := make(map[string]float64)
+	for _, sugg := range res.Suggestions {
+		if sugg.Source == "solr" && sugg.Score > maxSolrScore[sugg.Type] {
+			maxSolrScore[sugg.Type] = sugg.Score
+		}
+	}
+
+	sigs := make([][signalCount]float64, len(res.Suggestions))
+	for i, sugg := range res.Suggestions {
+		sigs[i] = s.signals(sugg, maxSolrScore)
+	}
+
+	weights := s.svc.rankWeights()
+
+	var scores []float64
+	if s.svc.config.Ranking.Method == rankMethodRRF {
+		scores = fuseRRF(sigs, weights, s.svc.config.Ranking.RRFK)
+	} else {
+		scores = fuseWeighted(sigs, weights)
+	}
+
+	for i := range res.Suggestions {
+		res.Suggestions[i].Score = math.Round(scores[i]*rankScorePrecision) / rankScorePrecision
+		if s.verbose == true {
+			log.Printf("[RANK] %s '%s' signals=%v score=%.4f", res.Suggestions[i].Type, res.Suggestions[i].Value, sigs[i], res.Suggestions[i].Score)
+		}
+	}
+
+	sort.SliceStable(res.Suggestions, func(i, j int) bool {
+		return rankLess(res.Suggestions[i], res.Suggestions[j])
+	})
+
+	res.splitByType()
+}
+
+// splitByType rebuilds the per-type lists from the combined list, keeping its order
+func (res *SuggestionResponse) splitByType() {
+	byType := func(suggType string) []Suggestion {
+		var list []Suggestion
+		for _, sugg := range res.Suggestions {
+			if sugg.Type == suggType {
+				list = append(list, sugg)
+			}
+		}
+		return list
+	}
+
+	res.Authors = byType("author")
+	res.Books = byType("book")
+	res.Images = byType("image")
+	res.Subjects = byType("subject")
+	res.Series = byType("series")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFuseWeighted(t *testing.T) {
+	weights := [signalCount]float64{signalRetrieval: 1, signalLLM: 1, signalSource: 2}
+
+	tests := []struct {
+		name    string
+		sig     [signalCount]float64
+		weights [signalCount]float64
+		want    float64
+	}{
+		{"weighted mean", [signalCount]float64{signalRetrieval: 1, signalLLM: 0.5, signalSource: 0.5}, weights, 0.625},
+		{"unweighted signals ignored", [signalCount]float64{signalRetrieval: 1, signalPopularity: 1, signalSource: 1}, weights, 0.75},
+		{"no signals", [signalCount]float64{}, weights, 0},
+		{"no weights", [signalCount]float64{signalRetrieval: 1}, [signalCount]float64{}, 0},
+	}
+
+	for _, tt := range tests {
+		got := fuseWeighted([][signalCount]float64{tt.sig}, tt.weights)
+		if math.Abs(got[0]-tt.want) > 1e-9 {
+			t.Errorf("%s: fuseWeighted = %.4f, want %.4f", tt.name, got[0], tt.want)
+		}
+	}
+}
+
+func TestFuseRRF(t *testing.T) {
+	weights := [signalCount]float64{signalRetrieval: 1, signalSource: 1}
+
+	tests := []struct {
+		name string
+		sigs [][signalCount]float64
+		want []float64
+	}{
+		{
+			"ranked by each signal",
+			[][signalCount]float64{
+				{signalRetrieval: 0.5, signalSource: 0.8},
+				{signalRetrieval: 0.9, signalSource: 1},
+				{signalRetrieval: 0, signalSource: 0.5},
+			},
+			// a suggestion with no value for a signal gets nothing for it
+			[]float64{61.0 / 62, 1, 61.0 / 126},
+		},
+		{
+			// equal signals are ranked in input order, which rankSuggestions makes canonical
+			"ties",
+			[][signalCount]float64{
+				{signalRetrieval: 1, signalSource: 1},
+				{signalRetrieval: 1, signalSource: 1},
+			},
+			[]float64{1, 61.0 / 62},
+		},
+		{"empty", nil, []float64{}},
+	}
+
+	for _, tt := range tests {
+		got := fuseRRF(tt.sigs, weights, 60)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: fuseRRF = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: fuseRRF[%d] = %.4f, want %.4f", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	if got := fuseRRF([][signalCount]float64{{signalRetrieval: 1}}, [signalCount]float64{}, 60); got[0] != 0 {
+		t.Errorf("fuseRRF with no weights = %v, want [0]", got)
+	}
+}
+
+func TestRankLess(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Suggestion
+	}{
+		{"score", Suggestion{Score: 0.9, Source: "llm"}, Suggestion{Score: 0.8, Source: "hybrid"}},
+		{"source", Suggestion{Score: 0.8, Source: "kb"}, Suggestion{Score: 0.8, Source: "solr"}},
+		{"hybrid first", Suggestion{Score: 0.8, Source: "hybrid"}, Suggestion{Score: 0.8, Source: "kb"}},
+		{"count", Suggestion{Score: 0.8, Source: "kb", Count: 20}, Suggestion{Score: 0.8, Source: "kb", Count: 3}},
+		{"type", Suggestion{Score: 0.8, Type: "author"}, Suggestion{Score: 0.8, Type: "book"}},
+		{"value ignores case", Suggestion{Type: "author", Value: "clemens"}, Suggestion{Type: "author", Value: "Twain"}},
+		{"id", Suggestion{Type: "book", Value: "Roughing it", ID: "u1"}, Suggestion{Type: "book", Value: "roughing it", ID: "u2"}},
+	}
+
+	for _, tt := range tests {
+		if rankLess(tt.a, tt.b) == false {
+			t.Errorf("%s: rankLess(%+v, %+v) = false, want true", tt.name, tt.a, tt.b)
+		}
+		if rankLess(tt.b, tt.a) == true {
+			t.Errorf("%s: rankLess(%+v, %+v) = true, want false", tt.name, tt.b, tt.a)
+		}
+	}
+
+	same := Suggestion{Score: 0.8, Source: "kb", Type: "author", Value: "Twain"}
+	if rankLess(same, same) == true {
+		t.Errorf("rankLess(%+v, itself) = true, want false", same)
+	}
+}
+
+func TestRankSuggestions(t *testing.T) {
+	suggestions := []Suggestion{
+		{Type: "author", Value: "Twain, Mark", Source: "kb", Score: 0.8},
+		{Type: "author", Value: "Harte, Bret", Source: "solr", Score: 10},
+		{Type: "book", Value: "Roughing it", ID: "u1", Source: "kb", Score: 0.8},
+		{Type: "author", Value: "Clemens, Samuel", Source: "kb", Score: 0.8},
+		{Type: "subject", Value: "Humorists, American", Source: "llm"},
+	}
+
+	// retrieval and source weights give the first four the same score, so they are
+	// ordered by source, then type, then value
+	want := []string{"Clemens, Samuel", "Twain, Mark", "Roughing it", "Harte, Bret", "Humorists, American"}
+
+	for _, method := range []string{rankMethodWeighted, rankMethodRRF} {
+		cfg := &serviceConfig{Ranking: serviceConfigRanking{Method: method, RRFK: 60, RetrievalWeight: 1, SourceWeight: 1}}
+		s := &SuggestionContext{svc: &ServiceContext{config: cfg}}
+
+		// rotations of the list, forwards and backwards, as verification may finish in any order
+		var first []string
+		for shift := 0; shift < 2*len(suggestions); shift++ {
+			res := &SuggestionResponse{}
+			for i := range suggestions {
+				j := (i + shift) % len(suggestions)
+				if shift >= len(suggestions) {
+					j = len(suggestions) - 1 - j
+				}
+				res.Suggestions = append(res.Suggestions, suggestions[j])
+			}
+
+			s.rankSuggestions(res)
+
+			var got []string
+			for _, sugg := range res.Suggestions {
+				got = append(got, sugg.Value)
+			}
+
+			if first == nil {
+				first = got
+			} else if reflect.DeepEqual(got, first) == false {
+				t.Errorf("%s: order %q for input order %d, want %q", method, got, shift, first)
+			}
+
+			if method == rankMethodWeighted && reflect.DeepEqual(got, want) == false {
+				t.Errorf("%s: order %q, want %q", method, got, want)
+			}
+
+			if len(res.Authors) != 3 || res.Authors[0].Value != got[0] {
+				t.Errorf("%s: authors %+v not split in ranked order", method, res.Authors)
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// catalog facet fields that series and uniform title suggestions are grouped on
+const (
+	seriesFacetField       = "title_series_f"
+	uniformTitleFacetField = "title_uniform_f"
+)
+
+// series retrieval limits; the number of catalog records examined is set in config
+const (
+	seriesFacetLimit = 20 // facet values considered per facet field
+	seriesMinMembers = 2  // a "series" of one record is just a book
+)
+
+// seriesHit is a series or uniform title found in the catalog, along with
+// the catalog records from the query results that belong to it
+type seriesHit struct {
+	Name       string
+	FacetField string
+	Count      int
+	Similarity float64
+	Members    []Suggestion
+}
+
+// facetValues returns the values a catalog record holds for one of the series facet fields
+func (d SolrDocument) facetValues(field string) []string {
+	switch field {
+	case seriesFacetField:
+		return d.Series
+	case uniformTitleFacetField:
+		return d.UniformTitle
+	}
+	return nil
+}
+
+// displayTitle returns the best available title for a catalog record
+func (d SolrDocument) displayTitle() string {
+	if len(d.TitleA) > 0 {
+		return d.TitleA[0]
+	}
+	if len(d.Title) > 0 {
+		return d.Title[0]
+	}
+	return ""
+}
+
+// retrieveSeries searches the catalog core for series and uniform titles matching the query,
+// sharing results through the request cache
+func (s *SuggestionContext) retrieveSeries(query string) ([]seriesHit, error) {
+	sugg := s.svc.config.Suggestions.Series
+	limits := s.limits("series")
+
+	retrieve := func() (interface{}, error) {
+		solrReq := SolrRequest{Core: s.svc.config.Solr.CatalogCore}
+
+		solrReq.json.Params = SolrRequestParams{
+			Start:      0,
+			Rows:       limits.Retrieve,
+			DefType:    sugg.Params.DefType,
+			Fl:         sugg.Params.Fl,
+			Fq:         append(append([]string{}, sugg.Params.Fq...), s.filterQueries()...),
+			Q:          query,
+			Qf:         sugg.Params.Qf,
+			Sort:       sugg.Params.Sort,
+			Facet:      true,
+			FacetField: []string{seriesFacetField, uniformTitleFacetField},
+			FacetLimit: seriesFacetLimit,
+			FacetMin:   seriesMinMembers,
+		}
+
+		solrRes, err := s.SolrQuery(&solrReq)
+		if err != nil {
+			return nil, err
+		}
+
+		hits := []seriesHit{}
+		seen := make(map[string]bool)
+
+		for _, field := range solrReq.json.Params.FacetField {
+			values := solrRes.FacetCounts.FacetFields[field]
+
+			// facet values come back as a flat [value, count, value, count, ...] list
+			for i := 0; i+1 < len(values); i += 2 {
+				name, _ := values[i].(string)
+				count, _ := values[i+1].(float64)
+
+				key := strings.ToLower(strings.TrimSpace(name))
+				if key == "" || seen[key] == true {
+					continue
+				}
+
+				// the query itself must name the series; series that merely contain
+				// topically relevant records are not useful refinements
+				similarity, ok := isSimilar(query, name, "book")
+				if ok == false {
+					continue
+				}
+
+				seen[key] = true
+
+				hit := seriesHit{Name: name, FacetField: field, Count: int(count), Similarity: similarity}
+
+				for _, doc := range solrRes.Response.Docs {
+					for _, val := range doc.facetValues(field) {
+						if strings.EqualFold(val, name) {
+							hit.Members = append(hit.Members, Suggestion{Type: "book", ID: doc.ID, Value: doc.displayTitle(), Source: "solr"})
+							break
+						}
+					}
+				}
+
+				hits = append(hits, hit)
+			}
+		}
+
+		sort.SliceStable(hits, func(i, j int) bool {
+			if hits[i].Similarity != hits[j].Similarity {
+				return hits[i].Similarity > hits[j].Similarity
+			}
+			return hits[i].Count > hits[j].Count
+		})
+
+		if len(hits) > limits.Limit {
+			hits = hits[:limits.Limit]
+		}
+
+		return hits, nil
+	}
+
+	val, err := s.cachedRetrieval(fmt.Sprintf("series|%d|%d|%s|%s", limits.Retrieve, limits.Limit, s.filterKey(), query), retrieve)
+	hits, _ := val.([]seriesHit)
+
+	return hits, err
+}
+
+// groupSeriesBooks attaches knowledge base book hits to the series they belong to,
+// either because the catalog lists them as members or because the title names the series.
+// The hits may be shared through the request cache, so it works on a copy, and returns
+// that along with the set of grouped book IDs and lowercased titles.
+func groupSeriesBooks(shared []seriesHit, books []providers.BookHit) ([]seriesHit, map[string]bool) {
+	hits := make([]seriesHit, len(shared))
+	copy(hits, shared)
+
+	grouped := make(map[string]bool)
+
+	mark := func(keys ...string) {
+		for _, k := range keys {
+			if k != "" {
+				grouped[k] = true
+			}
+		}
+	}
+
+	for i := range hits {
+		hit := &hits[i]
+		hit.Members = append([]Suggestion{}, hit.Members...)
+
+		memberIDs := make(map[string]bool)
+		for _, m := range hit.Members {
+			memberIDs[m.ID] = true
+		}
+
+		for _, b := range books {
+			inSeries := memberIDs[b.ID] == true
+			if inSeries == false && strings.Contains(strings.ToLower(b.Title), strings.ToLower(hit.Name)) {
+				inSeries = true
+				hit.Members = append(hit.Members, Suggestion{Type: "book", ID: b.ID, Value: b.Title, Source: hitSource(b.Sources), Score: b.Score})
+			}
+
+			if inSeries == true {
+				mark(b.ID, strings.ToLower(b.Title))
+			}
+		}
+
+		for _, m := range hit.Members {
+			mark(m.ID, strings.ToLower(m.Value))
+		}
+	}
+
+	return hits, grouped
+}
+
+// seriesCandidates converts series hits into candidates
+func seriesCandidates(hits []seriesHit) []Suggestion {
+	var candidates []Suggestion
+
+	for _, hit := range hits {
+		reason := "Series matches your search query"
+		if hit.FacetField == uniformTitleFacetField {
+			reason = "Uniform title matches your search query"
+		}
+
+		candidates = append(candidates, Suggestion{
+			Type:       "series",
+			Value:      hit.Name,
+			Facet:      hit.Name,
+			FacetField: hit.FacetField,
+			Source:     "solr",
+			Reason:     reason,
+			Score:      hit.Similarity,
+			Count:      hit.Count,
+			Members:    hit.Members,
+		})
+	}
+
+	return candidates
+}
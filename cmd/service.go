@@ -10,6 +10,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -43,9 +44,21 @@ type ServiceSolr struct {
 type ServiceContext struct {
 	config     *serviceConfig
 	solr       ServiceSolr
+	prompts    map[string]promptVariant
 	AIProvider providers.AIProvider
+	Retriever  providers.Retriever // knowledge base search; the AI provider's unless a local index is configured
+
+	spellerMu      sync.RWMutex
+	spellCorrector *spellCorrector
+	graphMu        sync.RWMutex
+	authorGraph    *authorGraph
+	panels         *memoryCache
 }
 
+// refreshRetryInterval is how soon a failed background build (of the did-you-mean
+// dictionary or the author graph) is retried, e.g. while Solr is starting
+const refreshRetryInterval = time.Minute
+
 func integerWithMinimum(str string, min int) int {
 	val, err := strconv.Atoi(str)
 
@@ -101,8 +114,10 @@ func InitializeService(cfg *serviceConfig) *ServiceContext {
 	}
 
 	svc := ServiceContext{
-		config: cfg,
-		solr:   solr,
+		config:  cfg,
+		solr:    solr,
+		prompts: loadPromptVariants(cfg.AI.Prompts),
+		panels:  newMemoryCache(time.Duration(cfg.AuthorPanel.CacheMinutes) * time.Minute),
 	}
 
 	// Force specific model as our logic is currently model-tuned.
@@ -128,6 +143,23 @@ func InitializeService(cfg *serviceConfig) *ServiceContext {
 		log.Printf("[SERVICE] AI provider not configured or unknown: [%s]", cfg.AI.Provider)
 	}
 
+	// Initialize knowledge base retriever
+	log.Printf("[SERVICE] retriever            = [%s]", cfg.AI.Retriever.Type)
+	if cfg.AI.Retriever.Type == "local" {
+		log.Printf("[SERVICE] loading local knowledge base index from %s", cfg.AI.Retriever.Index)
+		retriever, err := providers.NewLocalRetriever(cfg.AI.Retriever.Index, nil)
+		if err != nil {
+			log.Printf("[SERVICE] FATAL: %s", err.Error())
+		} else {
+			svc.Retriever = retriever
+		}
+	} else if svc.AIProvider != nil {
+		svc.Retriever = svc.AIProvider
+	}
+
+	svc.startSpeller()
+	svc.startGraph()
+
 	return &svc
 }
 
@@ -190,41 +222,56 @@ func (svc *ServiceContext) HealthCheckHandler(c *gin.Context) {
 // AuthorSuggestionHandler takes a keyword search and suggests alternate
 // author searches that may provide better or more focused results
 func (svc *ServiceContext) AuthorSuggestionHandler(c *gin.Context) {
-	s := InitializeSuggestion(svc, c)
-
-	if err := c.BindJSON(&s.req); err != nil {
-		log.Printf("AuthorSuggestionHandler: invalid request: %s", err.Error())
-		c.String(http.StatusBadRequest, "Invalid request")
-		return
-	}
-
-	suggestions, err := s.HandleAuthorSuggestionRequest()
-
-	if err != nil {
-		log.Printf("ERROR: %s", err.Error())
-	}
-
-	c.JSON(http.StatusOK, suggestions)
+	res, status := svc.processSuggestionRequest(c, true)
+	c.JSON(status, res)
 }
 
 // SuggestionHandler takes a keyword search and suggests alternate searches
 // that may provide better or more focused results
 func (svc *ServiceContext) SuggestionHandler(c *gin.Context) {
+	res, status := svc.processSuggestionRequest(c, false)
+	c.JSON(status, res)
+}
+
+// processSuggestionRequest binds and runs a suggestion request, returning the
+// response along with the HTTP status it should be sent with
+func (svc *ServiceContext) processSuggestionRequest(c *gin.Context, authorsOnly bool) (*SuggestionResponse, int) {
 	s := InitializeSuggestion(svc, c)
 
-	if err := c.BindJSON(&s.req); err != nil {
-		log.Printf("SuggestionHandler: invalid request: %s", err.Error())
-		c.String(http.StatusBadRequest, "Invalid request")
-		return
+	if errs := s.bindSuggestionRequest(c); len(errs) > 0 {
+		log.Printf("invalid suggestion request")
+		res := &SuggestionResponse{Suggestions: []Suggestion{}, Errors: errs}
+		return res, statusForErrors(errs)
 	}
 
-	suggestions, err := s.HandleSuggestionRequest()
+	return s.runSuggestionRequest(authorsOnly)
+}
+
+// runSuggestionRequest runs an already-validated request through the author-only
+// or full suggestion process
+func (s *SuggestionContext) runSuggestionRequest(authorsOnly bool) (*SuggestionResponse, int) {
+	var suggestions *SuggestionResponse
+	var err error
+
+	if authorsOnly == true {
+		suggestions, err = s.HandleAuthorSuggestionRequest()
+	} else {
+		if err := s.resolvePrompts(); err != nil {
+			log.Printf("prompt rejected: %s", err.Error())
+			if errors.Is(err, errPromptForbidden) {
+				return errorResponse(errCodeForbidden, "aiPrompt", err)
+			}
+			return errorResponse(errCodeInvalidRequest, "promptId", err)
+		}
+
+		suggestions, err = s.HandleSuggestionRequest()
+	}
 
 	if err != nil {
 		log.Printf("ERROR: %s", err.Error())
 	}
 
-	c.JSON(http.StatusOK, suggestions)
+	return suggestions, s.finalizeResponse(suggestions)
 }
 
 func getBearerToken(authorization string) (string, error) {
@@ -244,19 +291,41 @@ func getBearerToken(authorization string) (string, error) {
 	return token, nil
 }
 
-// AuthenticateHandler ensures the request contains a valid token
-func (svc *ServiceContext) AuthenticateHandler(c *gin.Context) {
-	token, err := getBearerToken(c.GetHeader("Authorization"))
+func (svc *ServiceContext) validateAuthorization(authorization string) (*v4jwt.V4Claims, error) {
+	token, err := getBearerToken(authorization)
 	if err != nil {
-		log.Printf("authentication failed: [%s]", err.Error())
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+		return nil, fmt.Errorf("authentication failed: [%s]", err.Error())
 	}
 
 	claims, err := v4jwt.Validate(token, svc.config.Service.JWTKey)
 
 	if err != nil {
-		log.Printf("JWT signature for %s is invalid: %s", token, err.Error())
+		return nil, fmt.Errorf("JWT signature for %s is invalid: %s", token, err.Error())
+	}
+
+	return claims, nil
+}
+
+// optionalClaims returns the claims for the request token, if a valid one was supplied
+func (svc *ServiceContext) optionalClaims(c *gin.Context) *v4jwt.V4Claims {
+	if c.GetHeader("Authorization") == "" {
+		return nil
+	}
+
+	claims, err := svc.validateAuthorization(c.GetHeader("Authorization"))
+	if err != nil {
+		log.Printf("ignoring optional authorization: %s", err.Error())
+		return nil
+	}
+
+	return claims
+}
+
+// AuthenticateHandler ensures the request contains a valid token
+func (svc *ServiceContext) AuthenticateHandler(c *gin.Context) {
+	claims, err := svc.validateAuthorization(c.GetHeader("Authorization"))
+	if err != nil {
+		log.Printf("%s", err.Error())
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
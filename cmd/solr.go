@@ -50,16 +50,18 @@ type SolrResponseHeader struct {
 
 // SolrDocument is a single result record for a Solr request
 type SolrDocument struct {
-	ID         string   `json:"id,omitempty"`
-	Phrase     string   `json:"phrase,omitempty"`
-	Type       string   `json:"type,omitempty"`
-	Count      int      `json:"count,omitempty"`
-	Score      float64  `json:"score,omitempty"`
-	Title      []string `json:"title_display,omitempty"`
-	TitleA     []string `json:"title_a,omitempty"`
-	Author     []string `json:"author_facet,omitempty"`
-	AuthorA    []string `json:"author_a,omitempty"`
-	Identifier string   `json:"identifier,omitempty"`
+	ID           string   `json:"id,omitempty"`
+	Phrase       string   `json:"phrase,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	Count        int      `json:"count,omitempty"`
+	Score        float64  `json:"score,omitempty"`
+	Title        []string `json:"title_display,omitempty"`
+	TitleA       []string `json:"title_a,omitempty"`
+	Author       []string `json:"author_facet,omitempty"`
+	AuthorA      []string `json:"author_a,omitempty"`
+	Identifier   string   `json:"identifier,omitempty"`
+	Series       []string `json:"title_series_f,omitempty"`
+	UniformTitle []string `json:"title_uniform_f,omitempty"`
 }
 
 // SolrResponseDocuments is a set of result records for a Solr request, along with some metadata
@@ -92,6 +94,26 @@ type SolrResponse struct {
 	Status         string                `json:"status,omitempty"`
 }
 
+// quoteSolrValue quotes a value for use as an exact match in a Solr query
+func quoteSolrValue(val string) string {
+	val = strings.ReplaceAll(val, `\`, `\\`)
+	val = strings.ReplaceAll(val, `"`, `\"`)
+	return `"` + val + `"`
+}
+
+// solrPageSize is the number of documents fetched per request when paging through a core
+const solrPageSize = 10000
+
+// escapeSolrQuery escapes the Solr query syntax characters in user text, so that it is
+// searched for as words
+func escapeSolrQuery(val string) string {
+	val = strings.ReplaceAll(val, `\`, `\\`)
+	for _, char := range []string{"+", "-", "&", "|", "!", "(", ")", "{", "}", "[", "]", "^", `"`, "~", "*", "?", ":", "/"} {
+		val = strings.ReplaceAll(val, char, `\`+char)
+	}
+	return val
+}
+
 // SolrQuery performs an API request against Solr and returns the response, or an error
 func (s *SuggestionContext) SolrQuery(solrReq *SolrRequest) (*SolrResponse, error) {
 	ctx := s.svc.solr.service
@@ -136,7 +158,7 @@ func (s *SuggestionContext) SolrQuery(solrReq *SolrRequest) (*SolrResponse, erro
 
 		log.Printf("client.Do() failed: %s", resErr.Error())
 		log.Printf("ERROR: Failed response from %s %s - %d:%s. Elapsed Time: %d (ms)", reqType, url, status, errMsg, elapsedMS)
-		return nil, fmt.Errorf("failed to receive Solr response")
+		return nil, fmt.Errorf("failed to receive Solr response: %w", resErr)
 	}
 
 	log.Printf("[SOLR] http res: %5d ms", int64(time.Since(start)/time.Millisecond))
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// local spelling corrector defaults; all but the prefix length may be set in config
+const (
+	defaultSpellerMaxPhrases      = 200000
+	defaultSpellerMaxEdits        = 2
+	defaultSpellerMinCount        = 2
+	defaultSpellerConfidenceRatio = 5.0
+	defaultSpellerRefreshHours    = 24
+	spellerPrefixLength           = 7 // only the start of each word is indexed, as in SymSpell
+	spellerMinWordLength          = 4 // shorter words are too easily "corrected" into other words
+)
+
+// did-you-mean sources reported with the correction
+const (
+	dymSourceLocal = "local"
+	dymSourceLLM   = "llm"
+)
+
+// spellCorrector is a SymSpell-style spelling corrector over the words of the autocomplete
+// core phrases.  Each word's prior is the total record count of the phrases it appears in.
+// Words are indexed by every deletion of up to maxEdits characters from their prefix, so
+// a misspelling is looked up by its own deletions instead of by generating every edit.
+type spellCorrector struct {
+	words    map[string]int
+	deletes  map[string][]string
+	maxEdits int
+	builtAt  time.Time
+}
+
+// spellSuggestion is a dictionary word within reach of a misspelling
+type spellSuggestion struct {
+	word     string
+	distance int
+	count    int
+}
+
+// spellingWords splits text into lowercase words, keeping diacritics
+func spellingWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return unicode.IsLetter(r) == false && unicode.IsDigit(r) == false
+	})
+}
+
+// editDeletes returns every string formed by deleting up to maxEdits characters from word
+func editDeletes(word string, maxEdits int) map[string]bool {
+	deletes := map[string]bool{word: true}
+
+	frontier := []string{word}
+	for d := 0; d < maxEdits; d++ {
+		var next []string
+		for _, w := range frontier {
+			runes := []rune(w)
+			if len(runes) <= 1 {
+				continue
+			}
+			for i := range runes {
+				del := string(runes[:i]) + string(runes[i+1:])
+				if deletes[del] == false {
+					deletes[del] = true
+					next = append(next, del)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	return deletes
+}
+
+// prefix returns the indexed start of a word
+func prefix(word string) string {
+	runes := []rune(word)
+	if len(runes) > spellerPrefixLength {
+		return string(runes[:spellerPrefixLength])
+	}
+	return word
+}
+
+// newSpellCorrector indexes a dictionary of words and their counts
+func newSpellCorrector(words map[string]int, maxEdits int) *spellCorrector {
+	sc := &spellCorrector{
+		words:    words,
+		deletes:  make(map[string][]string),
+		maxEdits: maxEdits,
+		builtAt:  time.Now(),
+	}
+
+	for word := range words {
+		for del := range editDeletes(prefix(word), maxEdits) {
+			sc.deletes[del] = append(sc.deletes[del], word)
+		}
+	}
+
+	return sc
+}
+
+// allowedEdits returns the number of edits tolerated for a word of the given length
+func (sc *spellCorrector) allowedEdits(length int) int {
+	allowed := 1
+	if length >= 8 {
+		allowed = 2
+	}
+	return min(allowed, sc.maxEdits)
+}
+
+// lookup returns the dictionary words within reach of a word, closest and most common first
+func (sc *spellCorrector) lookup(word string) []spellSuggestion {
+	allowed := sc.allowedEdits(len([]rune(word)))
+
+	seen := make(map[string]bool)
+	var suggestions []spellSuggestion
+
+	for del := range editDeletes(prefix(word), allowed) {
+		for _, candidate := range sc.deletes[del] {
+			if seen[candidate] == true {
+				continue
+			}
+			seen[candidate] = true
+
+			if d := osaDistance(word, candidate); d <= allowed {
+				suggestions = append(suggestions, spellSuggestion{word: candidate, distance: d, count: sc.words[candidate]})
+			}
+		}
+	}
+
+	// closest first, then most common, then alphabetical so results are stable
+	for i := 1; i < len(suggestions); i++ {
+		for j := i; j > 0 && spellLess(suggestions[j], suggestions[j-1]); j-- {
+			suggestions[j], suggestions[j-1] = suggestions[j-1], suggestions[j]
+		}
+	}
+
+	return suggestions
+}
+
+// osaDistance returns the optimal string alignment distance between two words: the
+// Levenshtein distance, but with a swap of adjacent letters ("histroy") counted as one edit
+func osaDistance(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func spellLess(a, b spellSuggestion) bool {
+	if a.distance != b.distance {
+		return a.distance < b.distance
+	}
+	if a.count != b.count {
+		return a.count > b.count
+	}
+	return a.word < b.word
+}
+
+// correct tries to correct a query word by word.  It returns the corrected query and
+// whether the answer is confident: every word is either in the dictionary at least
+// minCount times, or has one correction that is clearly more common than any other at the
+// same distance.  A confident answer with no changes means the query needs no correction.
+func (sc *spellCorrector) correct(query string, minCount int, ratio float64) (string, bool) {
+	words := spellingWords(query)
+	if len(words) == 0 {
+		return "", false
+	}
+
+	corrected := query
+	changed := false
+
+	for _, word := range words {
+		if sc.words[word] >= minCount {
+			continue
+		}
+
+		// numbers, short words and other scripts are left to the LLM
+		if len([]rune(word)) < spellerMinWordLength || strings.IndexFunc(word, func(r rune) bool { return unicode.Is(unicode.Latin, r) == false }) >= 0 {
+			return "", false
+		}
+
+		// a word seen too rarely to be trusted is treated as a misspelling of a commoner one
+		var suggestions []spellSuggestion
+		for _, sugg := range sc.lookup(word) {
+			if sugg.word != word {
+				suggestions = append(suggestions, sugg)
+			}
+		}
+		if len(suggestions) == 0 || suggestions[0].count < minCount {
+			return "", false
+		}
+
+		best := suggestions[0]
+		if len(suggestions) > 1 && suggestions[1].distance == best.distance && float64(best.count) < ratio*float64(suggestions[1].count) {
+			log.Printf("[DYM-LOCAL] '%s' is ambiguous: '%s' (%d) or '%s' (%d)", word, best.word, best.count, suggestions[1].word, suggestions[1].count)
+			return "", false
+		}
+
+		corrected = replaceWord(corrected, word, best.word)
+		changed = true
+	}
+
+	if changed == false {
+		return "", true
+	}
+
+	return corrected, true
+}
+
+// replaceWord replaces the first whole-word, case-insensitive occurrence of word in text,
+// keeping the capitalization of its first letter
+func replaceWord(text string, word string, replacement string) string {
+	lower := strings.ToLower(text)
+	wordLen := len(word)
+
+	for start := 0; start < len(lower); {
+		i := strings.Index(lower[start:], word)
+		if i < 0 {
+			break
+		}
+		i += start
+		end := i + wordLen
+
+		before := i == 0 || isWordBoundary(lower[:i], true)
+		after := end == len(lower) || isWordBoundary(lower[end:], false)
+
+		if before && after && len(text) == len(lower) {
+			if r := []rune(text[i:end]); len(r) > 0 && unicode.IsUpper(r[0]) {
+				rr := []rune(replacement)
+				rr[0] = unicode.ToUpper(rr[0])
+				replacement = string(rr)
+			}
+			return text[:i] + replacement + text[end:]
+		}
+
+		start = end
+	}
+
+	return text
+}
+
+// isWordBoundary reports whether the rune next to a match ends a word
+func isWordBoundary(s string, last bool) bool {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return true
+	}
+	r := runes[0]
+	if last == true {
+		r = runes[len(runes)-1]
+	}
+	return unicode.IsLetter(r) == false && unicode.IsDigit(r) == false
+}
+
+// speller returns the current local spelling corrector, or nil if none has been built
+func (svc *ServiceContext) speller() *spellCorrector {
+	svc.spellerMu.RLock()
+	defer svc.spellerMu.RUnlock()
+	return svc.spellCorrector
+}
+
+// startSpeller builds the local spelling corrector in the background, and rebuilds it
+// periodically so that it follows changes to the autocomplete core
+func (svc *ServiceContext) startSpeller() {
+	cfg := svc.config.DidYouMean
+	if cfg.Disabled == true {
+		log.Printf("[DYM-LOCAL] local spelling correction is disabled")
+		return
+	}
+
+	go func() {
+		for {
+			wait := time.Duration(cfg.RefreshHours) * time.Hour
+			if err := svc.loadSpeller(); err != nil {
+				log.Printf("[DYM-LOCAL] failed to build dictionary (retrying in %v): %s", refreshRetryInterval, err.Error())
+				wait = refreshRetryInterval
+			}
+			time.Sleep(wait)
+		}
+	}()
+}
+
+// loadSpeller builds a spelling corrector from the autocomplete core phrases, most
+// common first, and replaces the current one with it
+func (svc *ServiceContext) loadSpeller() error {
+	cfg := svc.config.DidYouMean
+	s := &SuggestionContext{svc: svc}
+
+	start := time.Now()
+	words := make(map[string]int)
+	phrases := 0
+
+	for phrases < cfg.MaxPhrases {
+		solrReq := SolrRequest{}
+		solrReq.json.Params = SolrRequestParams{
+			Start: phrases,
+			Rows:  min(solrPageSize, cfg.MaxPhrases-phrases),
+			Q:     "*:*",
+			Fl:    []string{"phrase", "count"},
+			Fq:    cfg.Fq,
+			Sort:  "count desc",
+		}
+
+		solrRes, err := s.SolrQuery(&solrReq)
+		if err != nil {
+			return err
+		}
+
+		for _, doc := range solrRes.Response.Docs {
+			count := max(doc.Count, 1)
+			for _, w := range spellingWords(doc.Phrase) {
+				// dates and other numbers are never corrected
+				if strings.IndexFunc(w, unicode.IsDigit) >= 0 {
+					continue
+				}
+				words[w] += count
+			}
+		}
+
+		phrases += len(solrRes.Response.Docs)
+		if len(solrRes.Response.Docs) == 0 || phrases >= solrRes.Response.NumFound {
+			break
+		}
+	}
+
+	if len(words) == 0 {
+		return fmt.Errorf("no phrases found")
+	}
+
+	sc := newSpellCorrector(words, cfg.MaxEdits)
+
+	svc.spellerMu.Lock()
+	svc.spellCorrector = sc
+	svc.spellerMu.Unlock()
+
+	log.Printf("[DYM-LOCAL] built dictionary of %d words from %d phrases (took %v)", len(words), phrases, time.Since(start))
+
+	return nil
+}
+
+// localDidYouMean tries to answer did-you-mean from the local corrector.  It returns the
+// correction (empty if none is needed) and whether the local answer can be used; queries
+// in languages other than English, and ambiguous ones, are left to the LLM.
+func (s *SuggestionContext) localDidYouMean(query string) (string, bool) {
+	sc := s.svc.speller()
+	if sc == nil || isEnglish(s.language) == false {
+		return "", false
+	}
+
+	cfg := s.svc.config.DidYouMean
+
+	corrected, ok := sc.correct(query, cfg.MinCount, cfg.ConfidenceRatio)
+	if ok == false {
+		return "", false
+	}
+
+	if corrected == "" {
+		log.Printf("[DYM-LOCAL] '%s' needs no correction", query)
+	} else {
+		log.Printf("[DYM-LOCAL] '%s' corrected to '%s'", query, corrected)
+	}
+
+	return corrected, true
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testSpeller() *spellCorrector {
+	return newSpellCorrector(map[string]int{
+		"history": 50,
+		"of":      100,
+		"rome":    40,
+		"mark":    30,
+		"marck":   1,
+		"twain":   25,
+		"twin":    22,
+		"train":   20,
+		"rare":    1,
+	}, 2)
+}
+
+func TestSpellLookup(t *testing.T) {
+	sc := testSpeller()
+
+	tests := []struct {
+		word string
+		want []spellSuggestion
+	}{
+		{"histroy", []spellSuggestion{{"history", 1, 50}}},
+		{"twqin", []spellSuggestion{{"twain", 1, 25}, {"twin", 1, 22}}},
+		{"twain", []spellSuggestion{{"twain", 0, 25}, {"twin", 1, 22}, {"train", 1, 20}}},
+		{"zzzzz", nil},
+	}
+
+	for _, tt := range tests {
+		if got := sc.lookup(tt.word); reflect.DeepEqual(got, tt.want) == false {
+			t.Errorf("lookup(%q) = %+v, want %+v", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestSpellCorrect(t *testing.T) {
+	sc := testSpeller()
+
+	tests := []struct {
+		name      string
+		query     string
+		corrected string
+		ok        bool
+	}{
+		{"transposition", "histroy of rome", "history of rome", true},
+		{"capitalization", "Histroy of Rome", "History of Rome", true},
+		{"no correction needed", "history of rome", "", true},
+		{"rare word", "marck twain", "mark twain", true},
+		{"ambiguous", "twqin", "", false},
+		{"short word", "teh history", "", false},
+		{"other script", "история rome", "", false},
+		{"correction too rare", "raer", "", false},
+		{"no correction", "zzzzz", "", false},
+		{"no words", "  ", "", false},
+	}
+
+	for _, tt := range tests {
+		corrected, ok := sc.correct(tt.query, 2, 5)
+		if corrected != tt.corrected || ok != tt.ok {
+			t.Errorf("%s: correct(%q) = %q, %v; want %q, %v", tt.name, tt.query, corrected, ok, tt.corrected, tt.ok)
+		}
+	}
+}
+
+func TestReplaceWord(t *testing.T) {
+	tests := []struct {
+		text, word, replacement string
+		want                    string
+	}{
+		{"histroy of rome", "histroy", "history", "history of rome"},
+		{"Histroy of Rome", "histroy", "history", "History of Rome"},
+		{"TWIAN", "twian", "twain", "Twain"},
+		{"antwian twian", "twian", "twain", "antwian twain"},
+		{"twian, twian", "twian", "twain", "twain, twian"},
+		{"Straße twian", "twian", "twain", "Straße twain"},
+		{"mark twain", "twian", "twain", "mark twain"},
+	}
+
+	for _, tt := range tests {
+		if got := replaceWord(tt.text, tt.word, tt.replacement); got != tt.want {
+			t.Errorf("replaceWord(%q, %q, %q) = %q, want %q", tt.text, tt.word, tt.replacement, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -13,50 +12,76 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/uvalib/virgo4-jwt/v4jwt"
 	"github.com/uvalib/virgo4-parser/v4parser"
+	"github.com/uvalib/virgo4-suggestor-ws/names"
 	"github.com/uvalib/virgo4-suggestor-ws/providers"
 	"gonum.org/v1/gonum/stat"
 )
 
 // SuggestionContext contains data specific to this suggestion request
 type SuggestionContext struct {
-	svc         *ServiceContext
-	parser      v4parser.SolrParser
-	req         SuggestionRequest
-	parsedQuery string
-	verbose     bool
+	svc           *ServiceContext
+	parser        v4parser.SolrParser
+	req           SuggestionRequest
+	parsedQuery   string
+	queryFields   []providers.QueryField
+	language      providers.QueryLanguage
+	verbose       bool
+	claims        *v4jwt.V4Claims
+	authorPrompt  string
+	bookPrompt    string
+	subjectPrompt string
+	issuesMu      sync.Mutex
+	issues        []SuggestionError
+	cache         *suggestionCache
 }
 
 // Suggestion contains data for a single suggestion
 type Suggestion struct {
-	ID     string  `json:"id,omitempty"`
-	Type   string  `json:"type"`
-	Value  string  `json:"value"`
-	Facet  string  `json:"facet"`
-	IIIFID string  `json:"iiif_id,omitempty"`
-	Source string  `json:"source"`
-	Reason string  `json:"reason,omitempty"`
-	Score  float64 `json:"score,omitempty"`
+	ID         string       `json:"id,omitempty"`
+	Type       string       `json:"type"`
+	Value      string       `json:"value"`
+	Facet      string       `json:"facet"`
+	FacetField string       `json:"facet_field,omitempty"`
+	IIIFID     string       `json:"iiif_id,omitempty"`
+	Source     string       `json:"source"`
+	Reason     string       `json:"reason,omitempty"`
+	Score      float64      `json:"score,omitempty"`
+	Count      int          `json:"count,omitempty"`
+	Members    []Suggestion `json:"members,omitempty"`
+	Alternates []string     `json:"alternates,omitempty"` // other catalog forms of the same name
+	rank       rankSignals
 }
 
 // SuggestionRequest defines the format of a suggestion request
 type SuggestionRequest struct {
 	Query    string   `json:"query"`
 	AIPrompt string   `json:"aiPrompt"`
+	PromptID string   `json:"promptId"`
 	Debug    bool     `json:"debug"`
 	Features []string `json:"features"`
 	AuthorThreshold float64 `json:"authorThreshold"`
 	ImageThreshold  float64 `json:"imageThreshold"`
 	BookThreshold   float64 `json:"bookThreshold"`
+	Pool            string             `json:"pool"`
+	Filters         []SuggestionFilter `json:"filters"`
+	Limits          map[string]SuggestionLimits `json:"limits"`
 }
 
 // SuggestionResponse contains the full set of suggestions
 type SuggestionResponse struct {
 	DidYouMean  string              `json:"did_you_mean,omitempty"`
+	DidYouMeanHits *DidYouMeanHits  `json:"did_you_mean_hits,omitempty"`
+	DidYouMeanSource string         `json:"did_you_mean_source,omitempty"` // local or llm
 	Suggestions []Suggestion        `json:"suggestions"`
 	Authors     []Suggestion        `json:"authors"`
 	Images      []Suggestion        `json:"images"`
 	Books       []Suggestion        `json:"books"`
+	Subjects    []Suggestion        `json:"subjects"`
+	Series      []Suggestion        `json:"series"`
+	Warnings    []SuggestionError   `json:"warnings,omitempty"`
+	Errors      []SuggestionError   `json:"errors,omitempty"`
 	Metadata    map[string]*SuggestionMetadata `json:"metadata,omitempty"`
 }
 
@@ -73,6 +98,7 @@ type SuggestionMetadata struct {
 	Reasoning    string  `json:"reasoning,omitempty"`
 	CostPer1K    float64 `json:"cost_per_1k,omitempty"`
 	Model        string  `json:"model,omitempty"`
+	Source       string  `json:"source,omitempty"` // where a did-you-mean came from: local or llm
 }
 
 func calculateCostPer1K(model string, inputTokens, outputTokens int) float64 {
@@ -123,36 +149,90 @@ func InitializeSuggestion(svc *ServiceContext, c *gin.Context) *SuggestionContex
 
 	s.svc = svc
 	s.verbose = boolOptionWithFallback(c.Query("verbose"), false)
+	s.claims = svc.optionalClaims(c)
 
 	return s
 }
 
-// ParseQuery ensures that the incoming query is valid, and parses it
+// isAdmin reports whether this request was made with a valid admin token
+func (s *SuggestionContext) isAdmin() bool {
+	return s.claims != nil && s.claims.Role.String() == "admin"
+}
+
+// userID returns the user ID from the request token, if any
+func (s *SuggestionContext) userID() string {
+	if s.claims == nil {
+		return "anonymous"
+	}
+	return s.claims.UserID
+}
+
+// suggestableFields are the query fields whose text is useful for suggestions, in the
+// order their values are combined into the parsed query.  Other fields (filters, dates,
+// identifiers) do not describe what the user is looking for.
+var suggestableFields = []string{"keyword", "title", "author", "subject", "series"}
+
+// reNegatedClause matches a NOT'd fielded clause, whose text should not be suggested against
+var reNegatedClause = regexp.MustCompile(`(?i)\bNOT\s+(\w+)\s*:\s*\{([^}]*)\}`)
+
+// unescapeParserValue undoes the Solr escaping that v4parser applies to field values:
+// backslashes are quadrupled, other special characters doubly escaped, and quotes
+// singly escaped
+func unescapeParserValue(val string) string {
+	val = strings.ReplaceAll(val, `\\\\`, "\x00")
+	val = strings.ReplaceAll(val, `\\`, "")
+	val = strings.ReplaceAll(val, `\"`, `"`)
+	return strings.ReplaceAll(val, "\x00", `\`)
+}
+
+// cleanQueryValue reduces a field value to plain text: no escaping, quotes or extra whitespace
+func cleanQueryValue(val string) string {
+	val = strings.ReplaceAll(unescapeParserValue(val), `"`, " ")
+	return strings.Join(strings.Fields(val), " ")
+}
+
+// ParseQuery ensures that the incoming query is valid, and parses it.  The text of the
+// keyword, title, author, subject and series clauses (including those combined with
+// AND/OR) is combined into the parsed query, and the clauses are kept as field context.
 func (s *SuggestionContext) ParseQuery() error {
 	if _, err := v4parser.ConvertToSolrWithParser(&s.parser, s.req.Query); err != nil {
 		return err
 	}
 
-	total := len(s.parser.FieldValues)
+	negated := make(map[string]bool)
+	for _, m := range reNegatedClause.FindAllStringSubmatch(s.req.Query, -1) {
+		negated[strings.ToLower(m[1])+"|"+cleanQueryValue(m[2])] = true
+	}
 
-	// currently only handle single-keyword searches
+	var fields []providers.QueryField
+	var terms []string
 
-	if total == 1 && len(s.parser.FieldValues["keyword"]) == 1 {
+	for _, field := range suggestableFields {
+		for _, raw := range s.parser.FieldValues[field] {
+			val := cleanQueryValue(raw)
 
-		// and the keyword cannot be some form of a * query
+			// the value cannot be some form of a * query
+			if val == "" || strings.Trim(val, "* ") == "" {
+				continue
+			}
 
-		keyword := s.parser.FieldValues["keyword"][0]
+			if negated[field+"|"+val] == true {
+				continue
+			}
 
-		if keyword == "" || keyword == "*" {
-			return errors.New("ignoring blank/* keyword query")
+			fields = append(fields, providers.QueryField{Field: field, Value: val})
+			terms = append(terms, val)
 		}
+	}
 
-		s.parsedQuery = keyword
-
-		return nil
+	if len(terms) == 0 {
+		return fmt.Errorf("%w: no keyword, title, author, subject or series text to suggest against", errUnhandledQuery)
 	}
 
-	return errors.New("unhandled query")
+	s.parsedQuery = strings.Join(terms, " ")
+	s.queryFields = fields
+
+	return nil
 }
 
 // HandleAuthorSuggestionRequest takes a keyword query and tries to find suggested
@@ -160,9 +240,15 @@ func (s *SuggestionContext) ParseQuery() error {
 func (s *SuggestionContext) HandleAuthorSuggestionRequest() (*SuggestionResponse, error) {
 	sugg := s.svc.config.Suggestions.Author
 
+	limit := sugg.LegacyLimit
+	if limit == 0 {
+		limit = s.limits("author").Limit
+	}
+
 	res := &SuggestionResponse{Suggestions: []Suggestion{}}
 
 	if err := s.ParseQuery(); err != nil {
+		s.addWarning("query", err)
 		return res, err
 	}
 
@@ -182,6 +268,7 @@ func (s *SuggestionContext) HandleAuthorSuggestionRequest() (*SuggestionResponse
 
 	solrRes, err := s.SolrQuery(&solrReq)
 	if err != nil {
+		s.addWarning("solr", err)
 		return res, err
 	}
 
@@ -189,9 +276,29 @@ func (s *SuggestionContext) HandleAuthorSuggestionRequest() (*SuggestionResponse
 		return res, nil
 	}
 
+	cutoff := s.scoreCutoff(solrRes.Response.Docs)
+
+	for _, doc := range solrRes.Response.Docs {
+		if doc.Score < cutoff || len(res.Suggestions) >= limit {
+			break
+		}
+
+		res.Suggestions = append(res.Suggestions, Suggestion{Type: "author", Value: doc.Phrase})
+	}
+
+	if s.verbose == true {
+		log.Printf("authors  : %v", len(res.Suggestions))
+	}
+
+	return res, nil
+}
+
+// scoreCutoff returns the score a Solr hit must reach to stand out from the rest of
+// the results: two standard deviations above the mean score
+func (s *SuggestionContext) scoreCutoff(docs []SolrDocument) float64 {
 	scores := []float64{}
 
-	for i, doc := range solrRes.Response.Docs {
+	for i, doc := range docs {
 		if s.verbose == true {
 			log.Printf("%03d %03.2f %s", i, doc.Score, doc.Phrase)
 		}
@@ -208,8 +315,8 @@ func (s *SuggestionContext) HandleAuthorSuggestionRequest() (*SuggestionResponse
 
 	if s.verbose == true {
 		log.Printf("len      : %v", len(scores))
-		log.Printf("max      : %v", solrRes.Response.Docs[0].Score)
-		log.Printf("min      : %v", solrRes.Response.Docs[len(solrRes.Response.Docs)-1].Score)
+		log.Printf("max      : %v", docs[0].Score)
+		log.Printf("min      : %v", docs[len(docs)-1].Score)
 		log.Printf("mean     : %v", mean)
 		log.Printf("median   : %v", median)
 		log.Printf("variance : %v", variance)
@@ -217,19 +324,7 @@ func (s *SuggestionContext) HandleAuthorSuggestionRequest() (*SuggestionResponse
 		log.Printf("cutoff   : %v", cutoff)
 	}
 
-	for _, doc := range solrRes.Response.Docs {
-		if doc.Score < cutoff || len(res.Suggestions) >= sugg.Limit {
-			break
-		}
-
-		res.Suggestions = append(res.Suggestions, Suggestion{Type: "author", Value: doc.Phrase})
-	}
-
-	if s.verbose == true {
-		log.Printf("authors  : %v", len(res.Suggestions))
-	}
-
-	return res, nil
+	return cutoff
 }
 
 // HandleSuggestionRequest takes a keyword query and tries to find suggested searches
@@ -240,27 +335,35 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 	}
 	res := &SuggestionResponse{Suggestions: []Suggestion{}}
 
-	var authorMeta, bookMeta, imageMeta, dymMeta *SuggestionMetadata
+	var authorMeta, bookMeta, imageMeta, subjectMeta, seriesMeta, dymMeta *SuggestionMetadata
 	if s.req.Debug {
 		authorMeta = &SuggestionMetadata{}
 		bookMeta = &SuggestionMetadata{}
 		imageMeta = &SuggestionMetadata{}
+		subjectMeta = &SuggestionMetadata{}
+		seriesMeta = &SuggestionMetadata{}
 		dymMeta = &SuggestionMetadata{}
 	}
 
-	// Ensure query is parsed (though we might use the raw query for the LLM)
+	// queries with nothing we can suggest against never reach the LLM, the KB or solr
 	if err := s.ParseQuery(); err != nil {
-		if s.parsedQuery == "" {
-			s.parsedQuery = s.req.Query
-		}
+		s.addWarning("query", err)
+		return res, err
 	}
 
 	rawQuery := s.parsedQuery
-	if rawQuery == "" {
-		rawQuery = s.req.Query
+
+	s.language = detectLanguage(rawQuery)
+	if s.language.Code != "" {
+		log.Printf("[QUERY] Detected language: %s (%s)", s.language.Name, s.language.Code)
 	}
 
 	var ctxData providers.SuggestionContextData
+	ctxData.QueryFields = s.queryFields
+	ctxData.Language = s.language
+	ctxData.AuthorCandidates = s.limits("author").Candidates
+	ctxData.BookCandidates = s.limits("book").Candidates
+	ctxData.SubjectCandidates = s.limits("subject").Candidates
 	var wg sync.WaitGroup
 	// Wait for all 3 routines to finish with a suitable timeout (e.g. 3 seconds)
 	// so slow backends don't hold up the entire suggestion request.
@@ -269,6 +372,8 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 	hasImages := false
 	hasAuthor := len(s.req.Features) == 0
 	hasBooks := false
+	hasSubjects := false
+	hasSeries := false
 	hasDidYouMean := false
 	for _, f := range s.req.Features {
 		if f == "images" {
@@ -277,11 +382,33 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 			hasAuthor = true
 		} else if f == "book" || f == "books" {
 			hasBooks = true
+		} else if f == "subject" {
+			hasSubjects = true
+		} else if f == "series" {
+			hasSeries = true
 		} else if f == "didyoumean" {
 			hasDidYouMean = true
 		}
 	}
 
+	// did-you-mean is answered from the local dictionary when it is confident, and the
+	// LLM is only asked about queries the dictionary cannot settle
+	askDidYouMean := hasDidYouMean
+	if hasDidYouMean {
+		start := time.Now()
+		if corrected, ok := s.localDidYouMean(rawQuery); ok == true {
+			askDidYouMean = false
+			res.DidYouMean = corrected
+			if corrected != "" {
+				res.DidYouMeanSource = dymSourceLocal
+			}
+			if s.req.Debug {
+				dymMeta.Source = dymSourceLocal
+				dymMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
+			}
+		}
+	}
+
 	if hasImages {
 		wg.Add(1)
 	}
@@ -291,76 +418,205 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 	if hasBooks {
 		wg.Add(1)
 	}
+	if hasSubjects {
+		wg.Add(1)
+	}
+	if hasSeries {
+		wg.Add(1)
+	}
+
+	// cycle-1 goroutines work on their own variables and publish the results into the
+	// shared context when they finish.  Once cycle 1 is over (or has timed out) nothing
+	// more is published, so cycle 2 never reads context that is still being written.
+	var cycleMu sync.Mutex
+	cycleOpen := true
+	publish := func(apply func()) bool {
+		cycleMu.Lock()
+		defer cycleMu.Unlock()
+		if cycleOpen == false {
+			return false
+		}
+		apply()
+		return true
+	}
+	warn := func(source string, err error) {
+		publish(func() { s.addWarning(source, err) })
+	}
 
+	// author and book hits come from both the knowledge base (semantic) and the
+	// configured Solr query (lexical), run side by side and merged by rank
 	if hasAuthor {
 		go func() {
 			defer wg.Done()
-			if s.svc.AIProvider == nil {
-				return
-			}
 			start := time.Now()
-			log.Printf("[CYCLE-1] Starting KB retrieval (threshold=%.2f)", s.req.AuthorThreshold)
-			kbResults, err := s.svc.AIProvider.Retrieve(rawQuery, 10, s.req.AuthorThreshold)
-			if err != nil {
-				log.Printf("[CYCLE-1] KB warning: %s (took %v)", err.Error(), time.Since(start))
-				return
-			}
-			ctxData.KBAuthors = kbResults
-			if s.req.Debug {
-				authorMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
+			limit := s.limits("author").Retrieve
+			var kbResults, solrResults []providers.AuthorHit
+			var hwg sync.WaitGroup
+
+			if s.svc.Retriever != nil {
+				hwg.Add(1)
+				go func() {
+					defer hwg.Done()
+					log.Printf("[CYCLE-1] Starting KB retrieval (threshold=%.2f)", s.req.AuthorThreshold)
+					hits, err := s.retrieveAuthors(rawQuery, limit, s.req.AuthorThreshold)
+					if err != nil {
+						log.Printf("[CYCLE-1] KB warning: %s (took %v)", err.Error(), time.Since(start))
+						warn("authors", err)
+						return
+					}
+					kbResults = hits
+					log.Printf("[CYCLE-1] Finished KB retrieval (took %v)", time.Since(start))
+				}()
 			}
-			log.Printf("[CYCLE-1] Finished KB retrieval (took %v)", time.Since(start))
+
+			hwg.Add(1)
+			go func() {
+				defer hwg.Done()
+				hits, err := s.lexicalAuthors(rawQuery, limit)
+				if err != nil {
+					log.Printf("[CYCLE-1] Solr author warning: %s (took %v)", err.Error(), time.Since(start))
+					warn("solr", err)
+					return
+				}
+				solrResults = hits
+			}()
+
+			hwg.Wait()
+
+			merged := s.fuseAuthorHits(kbResults, solrResults, limit)
+			published := publish(func() {
+				ctxData.KBAuthors = merged
+				if s.req.Debug {
+					authorMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
+				}
+			})
+			log.Printf("[CYCLE-1] Finished author retrieval: %d KB + %d Solr hits -> %d merged (took %v, used: %v)", len(kbResults), len(solrResults), len(merged), time.Since(start), published)
 		}()
 	}
 
 	if hasImages {
 		go func() {
 			defer wg.Done()
-			if s.svc.AIProvider == nil {
+			if s.svc.Retriever == nil {
 				return
 			}
 			start := time.Now()
 			log.Printf("[CYCLE-1] Starting Image KB retrieval (threshold=%.2f)", s.req.ImageThreshold)
-			imageResults, err := s.svc.AIProvider.RetrieveImages(rawQuery, 20, s.req.ImageThreshold)
+			imageResults, err := s.retrieveImages(rawQuery, s.limits("image").Retrieve, s.req.ImageThreshold)
 			if err != nil {
 				log.Printf("[CYCLE-1] Image KB warning: %s (took %v)", err.Error(), time.Since(start))
+				warn("images", err)
 				return
 			}
-			ctxData.KBImages = imageResults
 			for _, img := range imageResults {
 				log.Printf("[KB-IMAGE-DEBUG] KB Image: %s, ID: %s, Score: %.4f", img.Title, img.ID, img.Score)
 			}
-			if s.req.Debug {
-				imageMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
-			}
-			log.Printf("[CYCLE-1] Finished Image KB retrieval (took %v)", time.Since(start))
+			published := publish(func() {
+				ctxData.KBImages = imageResults
+				if s.req.Debug {
+					imageMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
+				}
+			})
+			log.Printf("[CYCLE-1] Finished Image KB retrieval (took %v, used: %v)", time.Since(start), published)
 		}()
 	}
 
 	if hasBooks {
 		go func() {
 			defer wg.Done()
-			if s.svc.AIProvider == nil {
-				return
+			start := time.Now()
+			limit := s.limits("book").Retrieve
+			var kbResults, solrResults []providers.BookHit
+			var hwg sync.WaitGroup
+
+			if s.svc.Retriever != nil {
+				hwg.Add(1)
+				go func() {
+					defer hwg.Done()
+					log.Printf("[CYCLE-1] Starting Book KB retrieval (threshold=%.2f)", s.req.BookThreshold)
+					hits, err := s.retrieveBooks(rawQuery, limit, s.req.BookThreshold)
+					if err != nil {
+						log.Printf("[CYCLE-1] Book KB warning: %s (took %v)", err.Error(), time.Since(start))
+						warn("books", err)
+						return
+					}
+					kbResults = hits
+					log.Printf("[CYCLE-1] Finished Book KB retrieval (took %v)", time.Since(start))
+				}()
 			}
+
+			hwg.Add(1)
+			go func() {
+				defer hwg.Done()
+				hits, err := s.lexicalBooks(rawQuery, limit)
+				if err != nil {
+					log.Printf("[CYCLE-1] Solr book warning: %s (took %v)", err.Error(), time.Since(start))
+					warn("solr", err)
+					return
+				}
+				solrResults = hits
+			}()
+
+			hwg.Wait()
+
+			merged := s.fuseBookHits(kbResults, solrResults, limit)
+			for _, b := range merged {
+				log.Printf("[KB-DEBUG] KB Book: %s, ID: %s, Score: %.4f, Sources: %v", b.Title, b.ID, b.Score, b.Sources)
+			}
+			published := publish(func() {
+				ctxData.KBBooks = merged
+				if s.req.Debug {
+					bookMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
+				}
+			})
+			log.Printf("[CYCLE-1] Finished book retrieval: %d KB + %d Solr hits -> %d merged (took %v, used: %v)", len(kbResults), len(solrResults), len(merged), time.Since(start), published)
+		}()
+	}
+
+	// subject headings come from Solr rather than the knowledge base, so they
+	// are gathered even when no AI provider is configured
+	if hasSubjects {
+		go func() {
+			defer wg.Done()
 			start := time.Now()
-			log.Printf("[CYCLE-1] Starting Book KB retrieval (threshold=%.2f)", s.req.BookThreshold)
-			bookResults, err := s.svc.AIProvider.RetrieveBooks(rawQuery, 20, s.req.BookThreshold)
+			log.Printf("[CYCLE-1] Starting Solr subject retrieval")
+			subjectResults, err := s.retrieveSubjects(rawQuery, s.limits("subject").Retrieve)
 			if err != nil {
-				log.Printf("[CYCLE-1] Book KB warning: %s (took %v)", err.Error(), time.Since(start))
+				log.Printf("[CYCLE-1] Solr subject warning: %s (took %v)", err.Error(), time.Since(start))
+				warn("subjects", err)
 				return
 			}
-			ctxData.KBBooks = bookResults
-			for _, b := range bookResults {
-				log.Printf("[KB-DEBUG] KB Book: %s, ID: %s, Score: %.4f", b.Title, b.ID, b.Score)
-			}
-			if s.req.Debug {
-				bookMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
-			}
-			log.Printf("[CYCLE-1] Finished Book KB retrieval (took %v)", time.Since(start))
+			published := publish(func() {
+				ctxData.SolrSubjects = subjectResults
+				if s.req.Debug {
+					subjectMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
+				}
+			})
+			log.Printf("[CYCLE-1] Finished Solr subject retrieval: %d hits (took %v, used: %v)", len(subjectResults), time.Since(start), published)
 		}()
 	}
 
+	var seriesHits []seriesHit
+	if hasSeries {
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			log.Printf("[CYCLE-1] Starting Solr series retrieval")
+			hits, err := s.retrieveSeries(rawQuery)
+			if err != nil {
+				log.Printf("[CYCLE-1] Solr series warning: %s (took %v)", err.Error(), time.Since(start))
+				warn("series", err)
+				return
+			}
+			published := publish(func() {
+				seriesHits = hits
+				if s.req.Debug {
+					seriesMeta.Cycle1TimeMS = time.Since(start).Milliseconds()
+				}
+			})
+			log.Printf("[CYCLE-1] Finished Solr series retrieval: %d hits (took %v, used: %v)", len(hits), time.Since(start), published)
+		}()
+	}
 
 	done := make(chan struct{})
 	go func() {
@@ -373,8 +629,19 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 		// Use raw time.Since(startCycle1) for log messages, that's harmless
 	case <-time.After(10 * time.Second):
 		log.Printf("[CYCLE-1] TIMEOUT! Context gathering halted after 10s. Proceeding with partial context")
+		s.addIssue(SuggestionError{Code: errCodeTimeout, Source: "retrieval", Message: "context gathering timed out; proceeding with partial context"})
 	}
-	
+
+	// close cycle 1: anything still running is discarded when it finishes
+	cycleMu.Lock()
+	cycleOpen = false
+	cycleMu.Unlock()
+
+	// catalog-linked authors ground the author prompt, and are suggested themselves
+	if hasAuthor {
+		ctxData.GraphAuthors = s.graphAuthorHits(rawQuery, ctxData.KBAuthors)
+	}
+
 	var candidates []Suggestion
 	var dymRes *providers.AIDymResponse
 
@@ -382,6 +649,10 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 		var c2wg sync.WaitGroup
 		var mu sync.Mutex
 
+		// each LLM branch collects its own candidates; they are combined in a fixed
+		// order once all branches finish, so candidate order does not depend on timing
+		var authorCandidates, bookCandidates, subjectCandidateList []Suggestion
+
 		// 0. Handle KB-Only Bypass
 		kbOnly := false
 		for _, f := range s.req.Features {
@@ -399,7 +670,7 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 						Type:   "author",
 						Value:  a.Name,
 						Facet:  a.FacetLabel,
-						Source: "kb",
+						Source: hitSource(a.Sources),
 						Reason: "Author matches your research query",
 						Score:  a.Score,
 					})
@@ -411,12 +682,15 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 						Type:   "book",
 						Value:  b.Title,
 						ID:     b.ID,
-						Source: "kb",
+						Source: hitSource(b.Sources),
 						Reason: "Book metadata matches your query",
 						Score:  b.Score,
 					})
 				}
 			}
+			if hasSubjects {
+				candidates = append(candidates, subjectCandidates(ctxData.SolrSubjects)...)
+			}
 		} else {
 			// 1. Author Suggestions Branch
 			if hasAuthor {
@@ -427,15 +701,16 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 					if s.req.Debug {
 						startCycle2 = time.Now()
 					}
-					res, err := s.svc.AIProvider.GetAuthorSuggestions(rawQuery, s.req.AIPrompt, ctxData, s.req.Debug)
+					res, err := s.svc.AIProvider.GetAuthorSuggestions(rawQuery, s.authorPrompt, ctxData, s.req.Debug)
 					if err != nil {
 						log.Printf("[CYCLE-2] ERROR: Author AI failed: %s", err.Error())
+						s.addWarning("authors", err)
 						return
 					}
 					mu.Lock()
 					defer mu.Unlock()
 					var usage providers.AIUsage
-					s.processAIResponse(res, &candidates, &usage, ctxData)
+					s.processAIResponse(res, "author", &authorCandidates, &usage, ctxData)
 					if s.req.Debug {
 						authorMeta.Cycle2TimeMS = time.Since(startCycle2).Milliseconds()
 						if res != nil {
@@ -464,15 +739,16 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 					if s.req.Debug {
 						startCycle2 = time.Now()
 					}
-					res, err := s.svc.AIProvider.GetBookSuggestions(rawQuery, s.req.AIPrompt, ctxData, s.req.Debug)
+					res, err := s.svc.AIProvider.GetBookSuggestions(rawQuery, s.bookPrompt, ctxData, s.req.Debug)
 					if err != nil {
 						log.Printf("[CYCLE-2] ERROR: Book AI failed: %s", err.Error())
+						s.addWarning("books", err)
 						return
 					}
 					mu.Lock()
 					defer mu.Unlock()
 					var usage providers.AIUsage
-					s.processAIResponse(res, &candidates, &usage, ctxData)
+					s.processAIResponse(res, "book", &bookCandidates, &usage, ctxData)
 					if s.req.Debug {
 						bookMeta.Cycle2TimeMS = time.Since(startCycle2).Milliseconds()
 						if res != nil {
@@ -491,10 +767,48 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 					}
 				}()
 			}
+
+			// 3. Subject Suggestions Branch
+			if hasSubjects {
+				c2wg.Add(1)
+				go func() {
+					defer c2wg.Done()
+					var startCycle2 time.Time
+					if s.req.Debug {
+						startCycle2 = time.Now()
+					}
+					res, err := s.svc.AIProvider.GetSubjectSuggestions(rawQuery, s.subjectPrompt, ctxData, s.req.Debug)
+					if err != nil {
+						log.Printf("[CYCLE-2] ERROR: Subject AI failed: %s", err.Error())
+						s.addWarning("subjects", err)
+						return
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					var usage providers.AIUsage
+					s.processAIResponse(res, "subject", &subjectCandidateList, &usage, ctxData)
+					if s.req.Debug {
+						subjectMeta.Cycle2TimeMS = time.Since(startCycle2).Milliseconds()
+						if res != nil {
+							if res.InputPrompt != "" {
+								subjectMeta.InputPrompt = res.InputPrompt
+							}
+							if res.RawOutput != "" {
+								subjectMeta.RawOutput = res.RawOutput
+							}
+							if res.Reasoning != "" {
+								subjectMeta.Reasoning = res.Reasoning
+							}
+							subjectMeta.InputTokens += usage.InputTokens
+							subjectMeta.OutputTokens += usage.OutputTokens
+						}
+					}
+				}()
+			}
 		}
 
-		// 3. DidYouMean Branch (Parallel)
-		if hasDidYouMean {
+		// 4. DidYouMean Branch (Parallel)
+		if askDidYouMean {
 			c2wg.Add(1)
 			go func() {
 				defer c2wg.Done()
@@ -503,11 +817,13 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 					startCycle2 = time.Now()
 				}
 				var err error
-				dymRes, err = s.svc.AIProvider.GetDidYouMean(rawQuery, s.req.Debug)
+				dymRes, err = s.svc.AIProvider.GetDidYouMean(rawQuery, s.language, s.req.Debug)
 				if err != nil {
 					log.Printf("[CYCLE-2] ERROR: AI DidYouMean failed: %s", err.Error())
+					s.addWarning("didyoumean", err)
 				}
 				if s.req.Debug {
+					dymMeta.Source = dymSourceLLM
 					dymMeta.Cycle2TimeMS = time.Since(startCycle2).Milliseconds()
 					if dymRes != nil {
 						dymMeta.InputTokens += dymRes.Usage.InputTokens
@@ -521,12 +837,35 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 
 		c2wg.Wait()
 
+		candidates = append(candidates, authorCandidates...)
+		candidates = append(candidates, bookCandidates...)
+		candidates = append(candidates, subjectCandidateList...)
+
 		// Process DidYouMean
 		if dymRes != nil && dymRes.DidYouMean != "" && !strings.EqualFold(strings.TrimSpace(dymRes.DidYouMean), strings.TrimSpace(rawQuery)) {
-			res.DidYouMean = dymRes.DidYouMean
-			log.Printf("[CYCLE-2] AI DidYouMean produced: '%s'", res.DidYouMean)
+			// a correction into another language is a translation, not a correction
+			if dymLang := detectLanguage(dymRes.DidYouMean); isEnglish(s.language) == false && dymLang.Code != "" && dymLang.Code != s.language.Code {
+				log.Printf("[CYCLE-2] Discarding AI DidYouMean '%s': %s query was translated to %s", dymRes.DidYouMean, s.language.Name, dymLang.Name)
+			} else {
+				res.DidYouMean = dymRes.DidYouMean
+				res.DidYouMeanSource = dymSourceLLM
+				log.Printf("[CYCLE-2] AI DidYouMean produced: '%s'", res.DidYouMean)
+			}
 		}
 
+	} else if hasAuthor || hasBooks || hasSubjects || askDidYouMean {
+		s.addIssue(SuggestionError{Code: errCodeDependencyUnavailable, Source: "ai", Message: "AI provider is not configured"})
+	}
+
+	// a correction is only offered if the catalog shows that it improves the search
+	if res.DidYouMean != "" {
+		hits, ok := s.verifyDidYouMean(rawQuery, res.DidYouMean)
+		if ok == false {
+			res.DidYouMean = ""
+			res.DidYouMeanSource = ""
+		} else {
+			res.DidYouMeanHits = hits
+		}
 	}
 	// Fallback for Book hits if no AI results produced any books
 	if hasBooks {
@@ -544,7 +883,7 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 					Type:   "book",
 					Value:  b.Title,
 					ID:     b.ID,
-					Source: "kb",
+					Source: hitSource(b.Sources),
 					Score:  b.Score,
 					Reason: "Book metadata matches your query",
 				})
@@ -552,6 +891,28 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 		}
 	}
 
+	// Fallback for subject hits if no AI results produced any subjects
+	if hasSubjects {
+		hasAISubjects := false
+		for _, c := range candidates {
+			if c.Type == "subject" {
+				hasAISubjects = true
+				break
+			}
+		}
+		if !hasAISubjects {
+			log.Printf("[CYCLE-2] No AI subject candidates found. Falling back to %d Solr subject hits.", len(ctxData.SolrSubjects))
+			candidates = append(candidates, subjectCandidates(ctxData.SolrSubjects)...)
+		}
+	}
+
+	// graph neighbours come from catalog facets, so they are added with or without an AI provider
+	if hasAuthor && len(ctxData.GraphAuthors) > 0 {
+		graphHits := graphCandidates(ctxData.GraphAuthors, s.limits("author").Candidates)
+		log.Printf("[CYCLE-2] Adding %d graph author hits.", len(graphHits))
+		candidates = append(candidates, graphHits...)
+	}
+
 	// Always add Image candidates if requested and found
 	if hasImages && len(ctxData.KBImages) > 0 {
 		log.Printf("[CYCLE-2] Adding %d KB image hits.", len(ctxData.KBImages))
@@ -569,6 +930,20 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 		}
 	}
 
+	// Series group the books that belong to them, so those books are not also suggested individually
+	if hasSeries && len(seriesHits) > 0 {
+		hits, grouped := groupSeriesBooks(seriesHits, ctxData.KBBooks)
+		var ungrouped []Suggestion
+		for _, c := range candidates {
+			if c.Type == "book" && (grouped[c.ID] || grouped[strings.ToLower(c.Value)]) {
+				continue
+			}
+			ungrouped = append(ungrouped, c)
+		}
+		log.Printf("[CYCLE-2] Adding %d series hits (%d book candidates grouped under them).", len(seriesHits), len(candidates)-len(ungrouped))
+		candidates = append(ungrouped, seriesCandidates(hits)...)
+	}
+
 	if len(candidates) > 0 {
 		var startCycle3 time.Time
 		if s.req.Debug {
@@ -576,161 +951,181 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 		}
 		var vwg sync.WaitGroup
 		var mu sync.Mutex
-		seenAuthors := make(map[string]bool)
-		seenImages := make(map[string]bool)
+
+		// For KB-only mode, we skip verification and trust the Knowledge Base results
+		// to avoid filtering out valid items due to strict Solr matching.
+		kbOnly := false
+		for _, f := range s.req.Features {
+			if f == "kb-only" {
+				kbOnly = true
+				break
+			}
+		}
+
+		// Each candidate is verified in its own goroutine, which fills in the slot for
+		// its index (or leaves it nil if rejected).  The slots are then assembled in
+		// candidate order, so the output does not depend on which verification finished first.
+		verified := make([]*Suggestion, len(candidates))
 
 		log.Printf("[CYCLE-3] Starting parallel verification for %d candidates...", len(candidates))
-		for _, cand := range candidates {
+		for i, cand := range candidates {
 			vwg.Add(1)
-			go func(c Suggestion) {
+			go func(i int, c Suggestion) {
 				defer vwg.Done()
-				if c.Type == "image" {
-					mu.Lock()
-					defer mu.Unlock()
-					// Deduplicate based on IIIF ID if available, otherwise fallback to Facet (ID)
-					key := c.Facet
-					if c.IIIFID != "" {
-						key = c.IIIFID
-					}
-					if !seenImages[key] {
-						seenImages[key] = true
-						res.Images = append(res.Images, c)
-						res.Suggestions = append(res.Suggestions, c)
-					}
-					if s.req.Debug {
+
+				meta := authorMeta
+				switch c.Type {
+				case "image":
+					meta = imageMeta
+				case "book":
+					meta = bookMeta
+				case "series":
+					meta = seriesMeta
+				case "subject":
+					meta = subjectMeta
+				}
+
+				if s.req.Debug {
+					defer func() {
 						dur := time.Since(startCycle3).Milliseconds()
-						if dur > imageMeta.Cycle3TimeMS {
-							imageMeta.Cycle3TimeMS = dur
+						mu.Lock()
+						if dur > meta.Cycle3TimeMS {
+							meta.Cycle3TimeMS = dur
 						}
-					}
-					return
+						mu.Unlock()
+					}()
 				}
 
-				if c.Type == "book" {
+				switch c.Type {
+				case "image":
+					verified[i] = &c
+
+				case "book":
 					// If it's from the Knowledge Base, the ID is already the catalog_id (u...).
 					// We trust this ID and skip the extra Solr verification step.
-					if c.Source == "kb" && c.ID != "" {
-						mu.Lock()
-						defer mu.Unlock()
-						if !seenAuthors[c.Value] {
-							seenAuthors[c.Value] = true
+					if (c.Source == "kb" || c.Source == "hybrid") && c.ID != "" {
+						if s.inCurrentView(c.Type, c.Value, c.ID) == true {
 							log.Printf("[CYCLE-3] KB BOOK: Title=%s, ID=%s, Score=%.4f (Trusted)", c.Value, c.ID, c.Score)
-							res.Books = append(res.Books, c)
-							res.Suggestions = append(res.Suggestions, c)
-						}
-						if s.req.Debug {
-							dur := time.Since(startCycle3).Milliseconds()
-							if dur > bookMeta.Cycle3TimeMS {
-								bookMeta.Cycle3TimeMS = dur
-							}
+							verified[i] = &c
 						}
 						return
 					}
 
 					// For LLM-only suggestions or those missing IDs, we still verify to get the ID
 					// and ensure they actually exist in the catalog.
-					if canonical, id, ok := s.verifySuggestionResults(c.Value, c.Type); ok {
-						mu.Lock()
-						if !seenAuthors[canonical] {
-							seenAuthors[canonical] = true
-							c.Value = canonical
-							c.ID = id
-							log.Printf("[CYCLE-3] VERIFIED BOOK: Title=%s, ID=%s, Source=%s, Score=%.4f", c.Value, c.ID, c.Source, c.Score)
-							res.Books = append(res.Books, c)
-							res.Suggestions = append(res.Suggestions, c)
-						}
-						mu.Unlock()
+					if v, ok := s.verifySuggestionResults(c.Value, c.Type); ok {
+						c.Value = v.canonical
+						c.ID = v.id
+						c.rank.similarity = v.similarity
+						log.Printf("[CYCLE-3] VERIFIED BOOK: Title=%s, ID=%s, Source=%s, Score=%.4f", c.Value, c.ID, c.Source, c.Score)
+						verified[i] = &c
 					} else {
 						log.Printf("[CYCLE-3] REJECTED BOOK: Title=%s (Not found in catalog)", c.Value)
 					}
-					if s.req.Debug {
-						dur := time.Since(startCycle3).Milliseconds()
-						mu.Lock()
-						if dur > bookMeta.Cycle3TimeMS {
-							bookMeta.Cycle3TimeMS = dur
-						}
-						mu.Unlock()
+
+				case "series":
+					// series come straight from catalog facets, so they need no verification
+					log.Printf("[CYCLE-3] SERIES: Name=%s, Members=%d, Count=%d", c.Value, len(c.Members), c.Count)
+					verified[i] = &c
+
+				case "subject":
+					// Solr hits are taken straight from the autocomplete core, so they are
+					// already canonical headings.  LLM headings must be verified.
+					canonical := c.Value
+					ok := c.Source == "solr"
+					if ok == false {
+						var v verifiedSuggestion
+						v, ok = s.verifySuggestionResults(c.Value, c.Type)
+						canonical = v.canonical
+						c.Count = v.count
+						c.rank.similarity = v.similarity
+					} else {
+						ok = s.inCurrentView(c.Type, canonical, "")
 					}
-					return
-				}
-				
-				// For KB-only mode, we skip verification and trust the Knowledge Base results
-				// to avoid filtering out valid items due to strict Solr matching.
-				kbOnly := false
-				for _, f := range s.req.Features {
-					if f == "kb-only" {
-						kbOnly = true
-						break
+					if ok {
+						c.Value = canonical
+						c.Facet = canonical
+						log.Printf("[CYCLE-3] SUBJECT: Heading=%s, Source=%s, Score=%.4f", c.Value, c.Source, c.Score)
+						verified[i] = &c
+					} else {
+						log.Printf("[CYCLE-3] REJECTED SUBJECT: Heading=%s (Not found in catalog)", c.Value)
 					}
-				}
 
-				if kbOnly && c.Source == "kb" {
-					mu.Lock()
-					defer mu.Unlock()
-					if !seenAuthors[c.Value] {
-						seenAuthors[c.Value] = true
-						if c.Facet == "" {
-							c.Facet = c.Value
+				default:
+					// graph neighbours are catalog facets already
+					if (kbOnly && (c.Source == "kb" || c.Source == "hybrid")) || c.Source == "graph" {
+						facet := c.Facet
+						if facet == "" {
+							facet = c.Value
 						}
-						log.Printf("[CYCLE-3] KB-ONLY: Name=%s, Source=%s, Score=%.4f", c.Value, c.Source, c.Score)
-						res.Authors = append(res.Authors, c)
-						res.Suggestions = append(res.Suggestions, c)
-					}
-					if s.req.Debug {
-						dur := time.Since(startCycle3).Milliseconds()
-						if dur > authorMeta.Cycle3TimeMS {
-							authorMeta.Cycle3TimeMS = dur
+						if s.inCurrentView(c.Type, facet, "") == true {
+							log.Printf("[CYCLE-3] TRUSTED: Name=%s, Source=%s, Score=%.4f", c.Value, c.Source, c.Score)
+							verified[i] = &c
 						}
+						return
 					}
-					return
-				}
 
-					if canonical, _, ok := s.verifySuggestionResults(c.Value, c.Type); ok {
-						mu.Lock()
-						if !seenAuthors[canonical] {
-							seenAuthors[canonical] = true
-							c.Value = canonical // Replace candidate with exact catalog string
-							c.Facet = canonical // Populate link facet with exact catalog string
-							log.Printf("[CYCLE-3] VERIFIED: Name=%s, Source=%s, Score=%.4f", c.Value, c.Source, c.Score)
-							res.Authors = append(res.Authors, c)
-							res.Suggestions = append(res.Suggestions, c)
-						}
-						mu.Unlock()
+					if v, ok := s.verifySuggestionResults(c.Value, c.Type); ok {
+						c.Count = v.count
+						c.rank.similarity = v.similarity
+						c.Value = v.canonical // Replace candidate with exact catalog string
+						c.Facet = v.canonical // Populate link facet with exact catalog string
+						log.Printf("[CYCLE-3] VERIFIED: Name=%s, Source=%s, Score=%.4f", c.Value, c.Source, c.Score)
+						verified[i] = &c
 					}
-					if s.req.Debug {
-						dur := time.Since(startCycle3).Milliseconds()
-						mu.Lock()
-						if dur > authorMeta.Cycle3TimeMS {
-							authorMeta.Cycle3TimeMS = dur
-						}
-						mu.Unlock()
-					}
-			}(cand)
+				}
+			}(i, cand)
 		}
 		vwg.Wait()
-		
-		// Cap final author suggestions at 8 as requested, but preserve all images
-		var finalSugg []Suggestion
-		authCount := 0
-		for _, s := range res.Suggestions {
-			if s.Type == "author" || s.Type == "" {
-				if authCount < 8 {
-					finalSugg = append(finalSugg, s)
-					authCount++
+
+		// Assemble in candidate order.  When several candidates verify to the same
+		// suggestion, the earliest one wins.
+		seen := make(map[string]bool)
+		for _, c := range verified {
+			if c == nil {
+				continue
+			}
+
+			var key string
+			switch c.Type {
+			case "image":
+				// Deduplicate based on IIIF ID if available, otherwise fallback to Facet (ID)
+				key = c.Facet
+				if c.IIIFID != "" {
+					key = c.IIIFID
 				}
-			} else {
-				finalSugg = append(finalSugg, s)
+			case "series", "subject":
+				key = strings.ToLower(c.Facet)
+			default:
+				key = c.Value
 			}
-		}
-		res.Suggestions = finalSugg
-		
-		imgCount := 0
-		for _, s := range res.Suggestions {
-			if s.Type == "image" {
-				imgCount++
+
+			key = c.Type + "|" + key
+			if seen[key] == true {
+				continue
 			}
+
+			seen[key] = true
+
+			switch c.Type {
+			case "image":
+				res.Images = append(res.Images, *c)
+			case "book":
+				res.Books = append(res.Books, *c)
+			case "series":
+				res.Series = append(res.Series, *c)
+			case "subject":
+				res.Subjects = append(res.Subjects, *c)
+			default:
+				res.Authors = append(res.Authors, *c)
+			}
+			res.Suggestions = append(res.Suggestions, *c)
 		}
-		log.Printf("[DEBUG] Final Composition: authors=%d, images=%d, total=%d", authCount, imgCount, len(res.Suggestions))
+
+		s.attachResourceCounts(res)
+		s.rankSuggestions(res)
+		s.diversifySuggestions(res)
+		s.capSuggestions(res)
 	}
 
 	if s.req.Debug {
@@ -761,8 +1156,21 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 			imageMeta.TotalTimeMS = imageMeta.Cycle1TimeMS + imageMeta.Cycle2TimeMS + imageMeta.Cycle3TimeMS
 			res.Metadata["images"] = imageMeta
 		}
+		if hasSubjects {
+			subjectMeta.Model = modelUsed
+			subjectMeta.CostPer1K = calculateCostPer1K(modelUsed, subjectMeta.InputTokens, subjectMeta.OutputTokens)
+			subjectMeta.TotalTimeMS = subjectMeta.Cycle1TimeMS + subjectMeta.Cycle2TimeMS + subjectMeta.Cycle3TimeMS
+			res.Metadata["subjects"] = subjectMeta
+		}
+		if hasSeries {
+			seriesMeta.Model = modelUsed
+			seriesMeta.TotalTimeMS = seriesMeta.Cycle1TimeMS + seriesMeta.Cycle2TimeMS + seriesMeta.Cycle3TimeMS
+			res.Metadata["series"] = seriesMeta
+		}
 		if hasDidYouMean {
-			dymMeta.Model = modelUsed
+			if dymMeta.Source == dymSourceLLM {
+				dymMeta.Model = modelUsed
+			}
 			dymMeta.CostPer1K = calculateCostPer1K(modelUsed, dymMeta.InputTokens, dymMeta.OutputTokens)
 			dymMeta.TotalTimeMS = dymMeta.Cycle1TimeMS + dymMeta.Cycle2TimeMS + dymMeta.Cycle3TimeMS
 			res.Metadata["didyoumean"] = dymMeta
@@ -785,17 +1193,29 @@ func (s *SuggestionContext) HandleSuggestionRequest() (*SuggestionResponse, erro
 	return res, nil
 }
 
-func (s *SuggestionContext) processAIResponse(aiRes *providers.AIResponse, candidates *[]Suggestion, usage *providers.AIUsage, ctxData providers.SuggestionContextData) {
+// processAIResponse converts the suggestions from an AI response into candidates, using
+// defaultType for any suggestion the model did not give a type
+func (s *SuggestionContext) processAIResponse(aiRes *providers.AIResponse, defaultType string, candidates *[]Suggestion, usage *providers.AIUsage, ctxData providers.SuggestionContextData) {
 	if aiRes == nil {
 		return
 	}
 
+	llmRank := 0
+	maxCandidates := s.limits(defaultType).Candidates
+
 	for _, sugg := range aiRes.Suggestions {
 		trimmedName := strings.TrimSpace(sugg.Name)
 		if trimmedName == "" {
 			continue
 		}
 
+		// models do not always keep to the number asked for
+		if maxCandidates > 0 && llmRank >= maxCandidates {
+			break
+		}
+
+		llmRank++
+
 		cand := Suggestion{
 			ID:     sugg.ID,
 			Type:   sugg.Type,
@@ -804,10 +1224,16 @@ func (s *SuggestionContext) processAIResponse(aiRes *providers.AIResponse, candi
 			Source: sugg.Source,
 			Reason: sugg.Reason,
 			Score:  sugg.Score,
+			rank:   rankSignals{llmRank: llmRank},
 		}
 
+		// the model's own scores and source claims cannot be trusted; matching against
+		// the research below restores the real score and source for KB hits
+		cand.Score = 0
+		cand.Source = "llm"
+
 		if cand.Type == "" {
-			cand.Type = "author"
+			cand.Type = defaultType
 		}
 
 		// Verify against KB hits
@@ -815,19 +1241,41 @@ func (s *SuggestionContext) processAIResponse(aiRes *providers.AIResponse, candi
 			for _, kbAuth := range ctxData.KBAuthors {
 				if strings.EqualFold(strings.TrimRight(kbAuth.Name, "."), strings.TrimRight(trimmedName, ".")) {
 					cand.Score = kbAuth.Score
-					cand.Source = "kb"
+					cand.Source = hitSource(kbAuth.Sources)
 					break
 				}
 			}
+			// catalog-linked authors are already exact facets
+			if cand.Source == "llm" {
+				for _, hit := range ctxData.GraphAuthors {
+					if names.Key(hit.Name) == names.Key(trimmedName) {
+						cand.Value = hit.Name
+						cand.Facet = hit.Name
+						cand.Score = hit.Score
+						cand.Source = "graph"
+						break
+					}
+				}
+			}
 		} else if cand.Type == "book" {
 			for _, kbBook := range ctxData.KBBooks {
 				if strings.EqualFold(kbBook.Title, trimmedName) {
 					cand.Score = kbBook.Score
-					cand.Source = "kb"
+					cand.Source = hitSource(kbBook.Sources)
 					cand.ID = kbBook.ID
 					break
 				}
 			}
+		} else if cand.Type == "subject" {
+			// headings found in the Solr research are already canonical
+			for _, hit := range ctxData.SolrSubjects {
+				if strings.EqualFold(strings.TrimRight(hit.Name, "."), strings.TrimRight(trimmedName, ".")) {
+					cand.Value = hit.Name
+					cand.Score = hit.Score
+					cand.Source = "solr"
+					break
+				}
+			}
 		}
 
 		*candidates = append(*candidates, cand)
@@ -837,13 +1285,18 @@ func (s *SuggestionContext) processAIResponse(aiRes *providers.AIResponse, candi
 	usage.OutputTokens += aiRes.Usage.OutputTokens
 }
 
-// GetAuthorResourceCounts retrieves document counts for a list of authors from Solr
+// GetAuthorResourceCounts retrieves document counts for a list of authors from Solr.
+// When the request has filters, the counts are of catalog records in the filtered view.
 func (s *SuggestionContext) GetAuthorResourceCounts(authors []string) (map[string]int, error) {
 	counts := make(map[string]int)
 	if len(authors) == 0 {
 		return counts, nil
 	}
 
+	if s.hasFilters() == true {
+		return s.getFilteredAuthorResourceCounts(authors)
+	}
+
 	// Construct OR query for exact author facets
 	var queryParts []string
 	for _, a := range authors {
@@ -874,12 +1327,107 @@ func (s *SuggestionContext) GetAuthorResourceCounts(authors []string) (map[strin
 	return counts, nil
 }
 
+// getFilteredAuthorResourceCounts counts catalog records per author within the current view,
+// by faceting on the catalog author field
+func (s *SuggestionContext) getFilteredAuthorResourceCounts(authors []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	field := s.svc.config.Filters.ViewFields["author"]
+
+	var queryParts []string
+	for _, a := range authors {
+		queryParts = append(queryParts, quoteSolrValue(a))
+	}
+
+	solrReq := SolrRequest{Core: s.svc.config.Solr.CatalogCore}
+	solrReq.json.Params = SolrRequestParams{
+		Q:          "*:*",
+		Rows:       0,
+		Fq:         append([]string{fmt.Sprintf("%s:(%s)", field, strings.Join(queryParts, " OR "))}, s.filterQueries()...),
+		Facet:      true,
+		FacetField: []string{field},
+		FacetLimit: -1,
+		FacetMin:   1,
+	}
+
+	solrRes, err := s.SolrQuery(&solrReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// facet values come back as a flat [value, count, value, count, ...] list;
+	// only the requested authors are of interest, not their co-authors
+	requested := make(map[string]bool)
+	for _, a := range authors {
+		requested[a] = true
+	}
+
+	values := solrRes.FacetCounts.FacetFields[field]
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := values[i].(string)
+		count, _ := values[i+1].(float64)
+		if requested[name] == true {
+			counts[name] = int(count)
+		}
+	}
+
+	return counts, nil
+}
+
+// verifiedSuggestion is the catalog match found for a suggested name/title
+type verifiedSuggestion struct {
+	canonical  string  // exact catalog name/title
+	id         string  // catalog identifier, if the core has one
+	count      int     // catalog records, if the core has a count
+	similarity float64 // how closely the suggestion matched the canonical form
+}
+
 // verifySuggestionResults checks if a suggested name/title exists in the autocomplete core with hits
-// and returns the CANONICAL name/title and its catalog identifier if found.
-func (s *SuggestionContext) verifySuggestionResults(value string, suggType string) (string, string, bool) {
+// and returns the CANONICAL name/title, its catalog identifier and record count if found.  When the request
+// has filters, the suggestion must also have records in the filtered view.
+// Results are shared through the request cache, if there is one.
+func (s *SuggestionContext) verifySuggestionResults(value string, suggType string) (verifiedSuggestion, bool) {
+	type verification struct {
+		match verifiedSuggestion
+		ok    bool
+	}
+
+	lookup := func() (interface{}, error) {
+		match, ok, err := s.lookupSuggestion(value, suggType)
+		if err == nil && ok == true && suggType != "book" {
+			// books are looked up in the catalog core with the filters applied already
+			ok = s.inCurrentView(suggType, match.canonical, match.id)
+		}
+		return verification{match: match, ok: ok}, err
+	}
+
+	var val interface{}
+	var err error
+
+	if s.cache != nil {
+		val, err = s.cache.verifications.getOrCompute(suggType+"|"+value+"|"+s.filterKey(), lookup)
+	} else {
+		val, err = lookup()
+	}
+
+	if err != nil {
+		// Log error but fail CLOSED -- we don't want to show suggestions we can't verify
+		log.Printf("[CYCLE-3] Solr error for '%s %s': %v (Failing closed)", suggType, value, err)
+		s.addWarning("verification", err)
+		return verifiedSuggestion{}, false
+	}
+
+	v := val.(verification)
+
+	return v.match, v.ok
+}
+
+// lookupSuggestion searches the catalog for the best match to a suggested name/title
+func (s *SuggestionContext) lookupSuggestion(value string, suggType string) (verifiedSuggestion, bool, error) {
 	var sugg serviceConfigSuggestion
 	if suggType == "book" {
 		sugg = s.svc.config.Suggestions.Book
+	} else if suggType == "subject" {
+		sugg = s.svc.config.Suggestions.Subject
 	} else {
 		sugg = s.svc.config.Suggestions.Author
 	}
@@ -887,7 +1435,7 @@ func (s *SuggestionContext) verifySuggestionResults(value string, suggType strin
 	// Standard filters often include count:[2 TO *] to avoid niche results.
 	// For AI-suggested authors, we relax this to count:[1 TO *] because we 
 	// trust the AI's relevance judgment and want to show enriched researchers.
-	// Books in test_core don't have a 'count' field, so we skip this for books.
+	// Books in the catalog core don't have a 'count' field, so we skip this for books.
 	fq := make([]string, len(sugg.Params.Fq))
 	copy(fq, sugg.Params.Fq)
 	if suggType != "book" {
@@ -923,7 +1471,7 @@ func (s *SuggestionContext) verifySuggestionResults(value string, suggType strin
 	}
 
 	if suggType == "book" {
-		solrReq.Core = "test_core"
+		solrReq.Core = s.svc.config.Solr.CatalogCore
 	}
 
 	// If no filters were provided in config, ensure we at least match the type
@@ -931,11 +1479,14 @@ func (s *SuggestionContext) verifySuggestionResults(value string, suggType strin
 		solrReq.json.Params.Fq = []string{fmt.Sprintf("type:%s", suggType)}
 	}
 
+	// books are looked up in the catalog core, so the user's current view applies directly
+	if suggType == "book" {
+		solrReq.json.Params.Fq = append(solrReq.json.Params.Fq, s.filterQueries()...)
+	}
+
 	solrRes, err := s.SolrQuery(&solrReq)
 	if err != nil {
-		// Log error but fail CLOSED -- we don't want to show suggestions we can't verify
-		log.Printf("[CYCLE-3] Solr error for '%s %s': %v (Failing closed)", suggType, value, err)
-		return "", "", false
+		return verifiedSuggestion{}, false, err
 	}
 
 	if solrRes.Response.NumFound > 0 && len(solrRes.Response.Docs) > 0 {
@@ -967,7 +1518,7 @@ func (s *SuggestionContext) verifySuggestionResults(value string, suggType strin
 		}
 		if bestCanonical != "" {
 			log.Printf("[CYCLE-3] Repaired '%s %s' -> '%s' (ID=%s, %d hits, similarity %0.2f)", suggType, value, bestCanonical, bestID, bestCount, bestScore)
-			return bestCanonical, bestID, true
+			return verifiedSuggestion{canonical: bestCanonical, id: bestID, count: bestCount, similarity: bestScore}, true, nil
 		}
 
 		// If we got hits but none were similar, log the top failure for diagnostics
@@ -977,62 +1528,129 @@ func (s *SuggestionContext) verifySuggestionResults(value string, suggType strin
 		}
 		topScore, _ := isSimilar(value, topCanon, suggType)
 		log.Printf("[CYCLE-3] Rejecting '%s %s' -> Top candidate '%s' has similarity too low (%0.2f)", suggType, value, topCanon, topScore)
-		return "", "", false
+		return verifiedSuggestion{}, false, nil
 	}
 
 	log.Printf("[CYCLE-3] Rejecting '%s %s' (No catalog results found with 75%% word match)", suggType, value)
-	return "", "", false
+	return verifiedSuggestion{}, false, nil
 }
 
 // isSimilar performs a basic similarity check between original and canonical names.
 // It ensures that we don't 'repair' a name into something completely unrelated.
+// Names are compared with diacritics, romanization variants, initials and word order
+// taken into account; dates are ignored except in book titles, where a number such as
+// "1984" might be the title.
 func isSimilar(orig, canon string, suggType string) (float64, bool) {
-	clean := func(s string) []string {
-		// 1. Strip catalog-specific leading symbols (e.g., * in *Wenger, Jared)
-		s = strings.TrimLeft(s, "*\"' ")
-
-		// 2. Remove dates: comma followed by digits and optional dash (e.g., ", 1973-")
-		// We skip this for books because 4-digit numbers might be part of the title (e.g., "1984")
-		if suggType != "book" {
-			reDates := regexp.MustCompile(`,?\s*\d{4}[-\d]*`)
-			s = reDates.ReplaceAllString(s, "")
-		}
+	// Aim for at least 60% of original words found in canonical.
+	score := names.Similarity(orig, canon, suggType != "book")
+	return score, score >= 0.60
+}
 
-		// 3. Remove roles and descriptions in parentheses (e.g., "(editor)")
-		reRoles := regexp.MustCompile(`\(.*?\)`)
-		s = reRoles.ReplaceAllString(s, "")
+// HandlePingRequest sends a ping request to Solr and checks the response.
+func (s *SuggestionContext) HandlePingRequest() error {
+	return s.SolrPing()
+}
 
-		// Standardize punctuation and split
-		s = strings.ReplaceAll(s, ",", " ")
-		s = strings.ReplaceAll(s, ".", " ")
-		return strings.Fields(strings.ToLower(s))
+// retrieveAuthors queries the knowledge base for author hits, sharing results through the request cache
+func (s *SuggestionContext) retrieveAuthors(query string, limit int, threshold float64) ([]providers.AuthorHit, error) {
+	retrieve := func() (interface{}, error) {
+		return s.svc.Retriever.Retrieve(query, limit, threshold)
 	}
 
-	oWords := clean(orig)
-	cWords := clean(canon)
+	val, err := s.cachedRetrieval(fmt.Sprintf("authors|%d|%f|%s", limit, threshold, query), retrieve)
+	hits, _ := val.([]providers.AuthorHit)
+
+	return hits, err
+}
 
-	if len(oWords) == 0 || len(cWords) == 0 {
-		return 0, false
+// retrieveImages queries the knowledge base for image hits, sharing results through the request cache
+func (s *SuggestionContext) retrieveImages(query string, limit int, threshold float64) ([]providers.ImageHit, error) {
+	retrieve := func() (interface{}, error) {
+		return s.svc.Retriever.RetrieveImages(query, limit, threshold)
 	}
 
-	// Basic check: see how many words from original are in canonical
-	matches := 0
-	for _, ow := range oWords {
-		for _, cw := range cWords {
-			if ow == cw {
-				matches++
-				break
+	val, err := s.cachedRetrieval(fmt.Sprintf("images|%d|%f|%s", limit, threshold, query), retrieve)
+	hits, _ := val.([]providers.ImageHit)
+
+	return hits, err
+}
+
+// retrieveBooks queries the knowledge base for book hits, sharing results through the request cache
+func (s *SuggestionContext) retrieveBooks(query string, limit int, threshold float64) ([]providers.BookHit, error) {
+	retrieve := func() (interface{}, error) {
+		return s.svc.Retriever.RetrieveBooks(query, limit, threshold)
+	}
+
+	val, err := s.cachedRetrieval(fmt.Sprintf("books|%d|%f|%s", limit, threshold, query), retrieve)
+	hits, _ := val.([]providers.BookHit)
+
+	return hits, err
+}
+
+// retrieveSubjects queries the autocomplete core for subject headings matching the query,
+// sharing results through the request cache
+func (s *SuggestionContext) retrieveSubjects(query string, limit int) ([]providers.SubjectHit, error) {
+	sugg := s.svc.config.Suggestions.Subject
+
+	retrieve := func() (interface{}, error) {
+		solrReq := SolrRequest{}
+
+		solrReq.json.Params = SolrRequestParams{
+			Start:   0,
+			Rows:    limit,
+			DefType: sugg.Params.DefType,
+			Fl:      sugg.Params.Fl,
+			Fq:      sugg.Params.Fq,
+			Q:       query,
+			Qf:      sugg.Params.Qf,
+			Sort:    sugg.Params.Sort,
+		}
+
+		solrRes, err := s.SolrQuery(&solrReq)
+		if err != nil {
+			return nil, err
+		}
+
+		hits := []providers.SubjectHit{}
+		for _, doc := range solrRes.Response.Docs {
+			if doc.Phrase == "" {
+				continue
 			}
+			hits = append(hits, providers.SubjectHit{Name: doc.Phrase, Count: doc.Count, Score: doc.Score})
 		}
+
+		return hits, nil
 	}
 
-	// For author names, we expect high overlap regardless of 'First Last' vs 'Last, First' order.
-	// threshold. Aim for at least 60% of original words found in canonical.
-	score := float64(matches) / float64(len(oWords))
-	return score, score >= 0.60
+	val, err := s.cachedRetrieval(fmt.Sprintf("subjects|%d|%s", limit, query), retrieve)
+	hits, _ := val.([]providers.SubjectHit)
+
+	return hits, err
 }
 
-// HandlePingRequest sends a ping request to Solr and checks the response.
-func (s *SuggestionContext) HandlePingRequest() error {
-	return s.SolrPing()
+// subjectCandidates converts Solr subject hits directly into candidates
+func subjectCandidates(hits []providers.SubjectHit) []Suggestion {
+	var candidates []Suggestion
+
+	for _, hit := range hits {
+		candidates = append(candidates, Suggestion{
+			Type:   "subject",
+			Value:  hit.Name,
+			Facet:  hit.Name,
+			Source: "solr",
+			Reason: "Subject heading matches your search query",
+			Score:  hit.Score,
+			Count:  hit.Count,
+		})
+	}
+
+	return candidates
+}
+
+func (s *SuggestionContext) cachedRetrieval(key string, retrieve func() (interface{}, error)) (interface{}, error) {
+	if s.cache == nil {
+		return retrieve()
+	}
+
+	return s.cache.retrievals.getOrCompute(key, retrieve)
 }
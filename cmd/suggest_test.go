@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		parsed string
+		fields []providers.QueryField
+	}{
+		{"keyword: {mark twain}", "mark twain", []providers.QueryField{{Field: "keyword", Value: "mark twain"}}},
+		{"title: {huckleberry finn}", "huckleberry finn", []providers.QueryField{{Field: "title", Value: "huckleberry finn"}}},
+		{
+			"title: {huckleberry finn} AND author: {twain}", "huckleberry finn twain",
+			[]providers.QueryField{{Field: "title", Value: "huckleberry finn"}, {Field: "author", Value: "twain"}},
+		},
+		{
+			"subject: {rivers} OR keyword: {mississippi}", "mississippi rivers",
+			[]providers.QueryField{{Field: "keyword", Value: "mississippi"}, {Field: "subject", Value: "rivers"}},
+		},
+		{"keyword: {mark twain} NOT author: {clemens}", "mark twain", []providers.QueryField{{Field: "keyword", Value: "mark twain"}}},
+		{"keyword: {\"mark twain\"}", "mark twain", []providers.QueryField{{Field: "keyword", Value: "mark twain"}}},
+	}
+
+	for _, tt := range tests {
+		s := &SuggestionContext{req: SuggestionRequest{Query: tt.query}}
+		if err := s.ParseQuery(); err != nil {
+			t.Errorf("ParseQuery(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if s.parsedQuery != tt.parsed {
+			t.Errorf("ParseQuery(%q) parsed = %q, want %q", tt.query, s.parsedQuery, tt.parsed)
+		}
+		if reflect.DeepEqual(s.queryFields, tt.fields) == false {
+			t.Errorf("ParseQuery(%q) fields = %+v, want %+v", tt.query, s.queryFields, tt.fields)
+		}
+	}
+
+	// queries with no text to suggest against
+	unhandled := []string{
+		"keyword: {*}",
+		"published: {1900}",
+		"keyword: {*} NOT author: {twain}",
+	}
+
+	for _, query := range unhandled {
+		s := &SuggestionContext{req: SuggestionRequest{Query: query}}
+		if err := s.ParseQuery(); errors.Is(err, errUnhandledQuery) == false {
+			t.Errorf("ParseQuery(%q) = %v, want %v", query, err, errUnhandledQuery)
+		}
+	}
+
+	s := &SuggestionContext{req: SuggestionRequest{Query: "keyword: {mark twain"}}
+	if err := s.ParseQuery(); err == nil {
+		t.Errorf("ParseQuery(%q) succeeded, want a parse error", s.req.Query)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// v1 suggestion kinds
+const (
+	v1KindAuthor  = "author"
+	v1KindBook    = "book"
+	v1KindImage   = "image"
+	v1KindSubject = "subject"
+	v1KindSeries  = "series"
+)
+
+// V1SuggestionResponse is the v1 response contract.  Each suggestion appears
+// exactly once, in ranked order, and carries a payload specific to its kind.
+type V1SuggestionResponse struct {
+	DidYouMean  *V1DidYouMean                  `json:"did_you_mean,omitempty"`
+	Suggestions []V1Suggestion                 `json:"suggestions"`
+	Warnings    []SuggestionError              `json:"warnings,omitempty"`
+	Errors      []SuggestionError              `json:"errors,omitempty"`
+	Metadata    map[string]*SuggestionMetadata `json:"metadata,omitempty"`
+}
+
+// V1DidYouMean contains a suggested correction for the query
+type V1DidYouMean struct {
+	Query     string `json:"query"`
+	Source    string `json:"source"`     // local or llm
+	Hits      int    `json:"hits"`       // catalog records the corrected query finds
+	QueryHits int    `json:"query_hits"` // catalog records the original query finds
+}
+
+// V1Suggestion is a single suggestion.  Kind is one of "author", "book", "image", "subject" or "series",
+// and exactly one of the matching payloads is present.
+type V1Suggestion struct {
+	Kind    string            `json:"kind"`
+	Label   string            `json:"label"`
+	Source  string            `json:"source"`
+	Reason  string            `json:"reason,omitempty"`
+	Score   float64           `json:"score"`
+	Count   int               `json:"count,omitempty"` // catalog records, where known
+	Author  *V1AuthorPayload  `json:"author,omitempty"`
+	Book    *V1BookPayload    `json:"book,omitempty"`
+	Image   *V1ImagePayload   `json:"image,omitempty"`
+	Subject *V1SubjectPayload `json:"subject,omitempty"`
+	Series  *V1SeriesPayload  `json:"series,omitempty"`
+}
+
+// V1AuthorPayload identifies an author search
+type V1AuthorPayload struct {
+	Facet      string   `json:"facet"`                // exact catalog author facet value to search on
+	Alternates []string `json:"alternates,omitempty"` // other catalog facet values for the same person
+}
+
+// V1BookPayload identifies a catalog record
+type V1BookPayload struct {
+	CatalogID string `json:"catalog_id"`
+}
+
+// V1ImagePayload identifies an image
+type V1ImagePayload struct {
+	ImageID string `json:"image_id"`
+	IIIFID  string `json:"iiif_id,omitempty"`
+}
+
+// V1SubjectPayload identifies a subject heading search
+type V1SubjectPayload struct {
+	Facet string `json:"facet"` // exact catalog subject heading to search on
+}
+
+// V1SeriesPayload identifies a series or uniform title search, along with the
+// matching catalog records that belong to it
+type V1SeriesPayload struct {
+	Facet       string          `json:"facet"`       // exact catalog facet value to search on
+	FacetField  string          `json:"facet_field"` // catalog facet the value belongs to
+	MemberCount int             `json:"member_count"`
+	Members     []V1BookPayload `json:"members"`
+}
+
+// toV1Suggestion converts an internal suggestion into its v1 form
+func toV1Suggestion(sugg Suggestion) V1Suggestion {
+	v1 := V1Suggestion{
+		Label:  sugg.Value,
+		Source: sugg.Source,
+		Reason: sugg.Reason,
+		Score:  sugg.Score,
+		Count:  sugg.Count,
+	}
+
+	switch sugg.Type {
+	case "book":
+		v1.Kind = v1KindBook
+		v1.Book = &V1BookPayload{CatalogID: sugg.ID}
+
+	case "image":
+		v1.Kind = v1KindImage
+		v1.Image = &V1ImagePayload{ImageID: sugg.ID, IIIFID: sugg.IIIFID}
+
+	case "subject":
+		v1.Kind = v1KindSubject
+		facet := sugg.Facet
+		if facet == "" {
+			facet = sugg.Value
+		}
+		v1.Subject = &V1SubjectPayload{Facet: facet}
+
+	case "series":
+		v1.Kind = v1KindSeries
+		v1.Series = &V1SeriesPayload{Facet: sugg.Facet, FacetField: sugg.FacetField, MemberCount: sugg.Count, Members: []V1BookPayload{}}
+		for _, m := range sugg.Members {
+			v1.Series.Members = append(v1.Series.Members, V1BookPayload{CatalogID: m.ID})
+		}
+
+	default:
+		v1.Kind = v1KindAuthor
+		facet := sugg.Facet
+		if facet == "" {
+			facet = sugg.Value
+		}
+		v1.Author = &V1AuthorPayload{Facet: facet, Alternates: sugg.Alternates}
+	}
+
+	return v1
+}
+
+// toV1Response adapts the internal (legacy) response shape to the v1 contract
+func toV1Response(res *SuggestionResponse) *V1SuggestionResponse {
+	v1 := &V1SuggestionResponse{
+		Suggestions: []V1Suggestion{},
+		Warnings:    res.Warnings,
+		Errors:      res.Errors,
+		Metadata:    res.Metadata,
+	}
+
+	if res.DidYouMean != "" {
+		v1.DidYouMean = &V1DidYouMean{Query: res.DidYouMean, Source: res.DidYouMeanSource}
+		if res.DidYouMeanHits != nil {
+			v1.DidYouMean.Hits = res.DidYouMeanHits.DidYouMean
+			v1.DidYouMean.QueryHits = res.DidYouMeanHits.Query
+		}
+	}
+
+	for _, sugg := range res.Suggestions {
+		v1.Suggestions = append(v1.Suggestions, toV1Suggestion(sugg))
+	}
+
+	return v1
+}
+
+// V1SuggestionHandler serves suggestions using the v1 response contract
+func (svc *ServiceContext) V1SuggestionHandler(c *gin.Context) {
+	res, status := svc.processSuggestionRequest(c, false)
+	c.JSON(status, toV1Response(res))
+}
+
+// V1AuthorSuggestionHandler serves lexical author suggestions using the v1 response contract
+func (svc *ServiceContext) V1AuthorSuggestionHandler(c *gin.Context) {
+	res, status := svc.processSuggestionRequest(c, true)
+	c.JSON(status, toV1Response(res))
+}
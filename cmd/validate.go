@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// request limits; the query length limit may be raised or lowered in config
+const (
+	defaultMaxQueryLength = 500
+	maxAIPromptLength     = 4000
+	minThreshold          = 0.0
+	maxThreshold          = 1.0
+	modelFeaturePrefix    = "llm:"
+)
+
+// requestFeature describes a value accepted in the features list of a suggestion request
+type requestFeature struct {
+	Name        string
+	Description string
+}
+
+// requestFeatures lists the accepted features, in the order they are documented
+var requestFeatures = []requestFeature{
+	{"author", "author suggestions (the default when no features are given)"},
+	{"book", "book and title suggestions"},
+	{"books", "alias for book"},
+	{"subject", "subject heading suggestions for topical searches"},
+	{"series", "series and uniform title suggestions, grouping the matching books"},
+	{"images", "image suggestions from the image knowledge base"},
+	{"didyoumean", "spelling correction for the query"},
+	{"kb-only", "author, book and subject suggestions straight from retrieval, skipping the LLM"},
+	{modelFeaturePrefix + "<model>", "report costs in debug metadata against the given model"},
+}
+
+func validFeatureNames() []string {
+	var names []string
+	for _, f := range requestFeatures {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func isValidFeature(feature string) bool {
+	if strings.HasPrefix(feature, modelFeaturePrefix) {
+		return len(feature) > len(modelFeaturePrefix)
+	}
+
+	for _, f := range requestFeatures {
+		if f.Name == feature {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stripControlCharacters replaces control characters with spaces and collapses runs of whitespace
+func stripControlCharacters(str string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, str)
+
+	return strings.Join(strings.Fields(cleaned), " ")
+}
+
+func clampThreshold(val float64) float64 {
+	if val < minThreshold {
+		return minThreshold
+	}
+	if val > maxThreshold {
+		return maxThreshold
+	}
+	return val
+}
+
+// maxQueryLength returns the configured query length limit
+func (svc *ServiceContext) maxQueryLength() int {
+	if svc.config.Service.MaxQueryLength > 0 {
+		return svc.config.Service.MaxQueryLength
+	}
+	return defaultMaxQueryLength
+}
+
+// normalizeAndValidate cleans up the request in place, and returns field-level errors
+// for anything that cannot be sensibly corrected
+func (s *SuggestionContext) normalizeAndValidate() []SuggestionError {
+	var errs []SuggestionError
+
+	fieldError := func(field string, format string, args ...interface{}) {
+		errs = append(errs, SuggestionError{Code: errCodeInvalidRequest, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	req := &s.req
+
+	req.Query = stripControlCharacters(req.Query)
+	maxLen := s.svc.maxQueryLength()
+
+	if req.Query == "" {
+		fieldError("query", "query is required")
+	} else if len([]rune(req.Query)) > maxLen {
+		fieldError("query", "query is longer than %d characters", maxLen)
+	}
+
+	if len([]rune(req.AIPrompt)) > maxAIPromptLength {
+		fieldError("aiPrompt", "aiPrompt is longer than %d characters", maxAIPromptLength)
+	}
+
+	req.PromptID = strings.TrimSpace(req.PromptID)
+
+	var features []string
+	for _, f := range req.Features {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if isValidFeature(f) == false {
+			fieldError("features", "unknown feature [%s]; valid features are: %s", f, strings.Join(validFeatureNames(), ", "))
+			continue
+		}
+		features = append(features, f)
+	}
+	req.Features = features
+
+	s.validateFilters(fieldError)
+	s.validateLimits(fieldError)
+
+	req.AuthorThreshold = clampThreshold(req.AuthorThreshold)
+	req.ImageThreshold = clampThreshold(req.ImageThreshold)
+	req.BookThreshold = clampThreshold(req.BookThreshold)
+
+	return errs
+}
+
+// bindSuggestionRequest binds, normalizes and validates the request body,
+// returning field-level errors for anything that is not acceptable
+func (s *SuggestionContext) bindSuggestionRequest(c *gin.Context) []SuggestionError {
+	if err := c.ShouldBindJSON(&s.req); err != nil {
+		log.Printf("invalid request: %s", err.Error())
+		return []SuggestionError{{Code: errCodeInvalidRequest, Source: "request", Message: err.Error()}}
+	}
+
+	errs := s.normalizeAndValidate()
+	for _, e := range errs {
+		log.Printf("invalid request: %s: %s", e.Field, e.Message)
+	}
+
+	return errs
+}
+
+// suggestionRequestSchema returns a JSON schema for SuggestionRequest, built from the same
+// limits that normalizeAndValidate enforces
+func (svc *ServiceContext) suggestionRequestSchema() map[string]interface{} {
+	var featureNames []string
+	var featureDocs []string
+	for _, f := range requestFeatures {
+		if strings.HasPrefix(f.Name, modelFeaturePrefix) == false {
+			featureNames = append(featureNames, f.Name)
+		}
+		featureDocs = append(featureDocs, fmt.Sprintf("`%s`: %s", f.Name, f.Description))
+	}
+
+	threshold := func(desc string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":        "number",
+			"minimum":     minThreshold,
+			"maximum":     maxThreshold,
+			"description": desc + " (out-of-range values are clamped)",
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema":  "https://json-schema.org/draft/2020-12/schema",
+		"title":    "SuggestionRequest",
+		"type":     "object",
+		"required": []string{"query"},
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"minLength":   1,
+				"maxLength":   svc.maxQueryLength(),
+				"description": "Virgo4 search query; control characters are stripped",
+			},
+			"promptId": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of a registered prompt variant",
+			},
+			"aiPrompt": map[string]interface{}{
+				"type":        "string",
+				"maxLength":   maxAIPromptLength,
+				"description": "free-form prompt template; requires an admin token",
+			},
+			"debug": map[string]interface{}{
+				"type":        "boolean",
+				"description": "include timing and token metadata in the response",
+			},
+			"features": map[string]interface{}{
+				"type":        "array",
+				"description": "suggestion types and options to enable. " + strings.Join(featureDocs, "; "),
+				"items": map[string]interface{}{
+					"anyOf": []interface{}{
+						map[string]interface{}{"type": "string", "enum": featureNames},
+						map[string]interface{}{"type": "string", "pattern": "^" + modelFeaturePrefix + ".+$"},
+					},
+				},
+			},
+			"pool": map[string]interface{}{
+				"type":        "string",
+				"description": "Virgo4 pool the user is searching; pools with configured filters: " + strings.Join(filterNames(svc.config.Filters.Pools), ", "),
+			},
+			"filters": map[string]interface{}{
+				"type":        "array",
+				"description": "facet filters active in the user's search; only suggestions with records in the filtered view are returned",
+				"items": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"facet_id", "value"},
+					"properties": map[string]interface{}{
+						"facet_id": map[string]interface{}{"type": "string", "enum": filterNames(svc.config.Filters.Facets)},
+						"value":    map[string]interface{}{"type": "string", "minLength": 1},
+					},
+				},
+			},
+			"limits":          svc.limitsSchema(),
+			"authorThreshold": threshold("minimum knowledge base score for author hits"),
+			"imageThreshold":  threshold("minimum knowledge base score for image hits"),
+			"bookThreshold":   threshold("minimum knowledge base score for book hits"),
+		},
+	}
+}
+
+// RequestSchemaHandler publishes the JSON schema for suggestion requests
+func (svc *ServiceContext) RequestSchemaHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, svc.suggestionRequestSchema())
+}
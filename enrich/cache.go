@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// negativeCacheFile is the name of the negative cache in the output directory
+const negativeCacheFile = ".enrich-negative.json"
+
+// negativeEntry records an author facet for which no usable article was found
+type negativeEntry struct {
+	Reason  string    `json:"reason"`
+	Checked time.Time `json:"checked"`
+}
+
+// negativeCache remembers the author facets with no usable article, so later runs do not
+// look them up again until the entry expires.  It is kept by facet rather than by title,
+// since an article that is about the wrong person for one facet may be right for another.
+type negativeCache struct {
+	path    string
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]negativeEntry
+}
+
+// loadNegativeCache reads the negative cache at path; a missing file is an empty cache
+func loadNegativeCache(path string, ttl time.Duration) (*negativeCache, error) {
+	c := &negativeCache{path: path, ttl: ttl, entries: make(map[string]negativeEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) == true {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// has reports whether a facet is in the cache and has not expired
+func (c *negativeCache) has(facet string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[facet]
+	return ok == true && time.Since(e.Checked) < c.ttl
+}
+
+// add records a facet with no usable article
+func (c *negativeCache) add(facet string, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[facet] = negativeEntry{Reason: reason, Checked: time.Now()}
+}
+
+// remove forgets a facet, once an article has been found for it
+func (c *negativeCache) remove(facet string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, facet)
+}
+
+// save writes the cache back to its file
+func (c *negativeCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, data, 0644)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNegativeCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), negativeCacheFile)
+
+	c, err := loadNegativeCache(path, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("loading a missing cache failed: %v", err)
+	}
+
+	c.add("Smith, John, 1950-", "article is about someone else")
+	c.add("Nobody, A.", "no article")
+	c.entries["Jones, Ann"] = negativeEntry{Reason: "no article", Checked: time.Now().Add(-25 * time.Hour)}
+	c.remove("Nobody, A.")
+
+	if err := c.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	c, err = loadNegativeCache(path, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("reloading the cache failed: %v", err)
+	}
+
+	tests := []struct {
+		facet string
+		want  bool
+	}{
+		{"Smith, John, 1950-", true},
+		{"Nobody, A.", false}, // removed once found
+		{"Jones, Ann", false}, // expired, so looked up again
+		{"Twain, Mark", false},
+	}
+
+	for _, tt := range tests {
+		if got := c.has(tt.facet); got != tt.want {
+			t.Errorf("has(%q) = %v, want %v", tt.facet, got, tt.want)
+		}
+	}
+
+	if e := c.entries["Smith, John, 1950-"]; e.Reason != "article is about someone else" {
+		t.Errorf("reloaded reason = %q, want %q", e.Reason, "article is about someone else")
+	}
+
+	// a shorter retry period expires entries sooner
+	c.ttl = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if c.has("Smith, John, 1950-") == true {
+		t.Errorf("has(%q) = true after the entry expired", "Smith, John, 1950-")
+	}
+}
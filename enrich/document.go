@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/uvalib/virgo4-suggestor-ws/kb"
+	"github.com/uvalib/virgo4-suggestor-ws/names"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// maxBioLength caps the biography held in metadata, which the service puts in prompts
+const maxBioLength = 1000
+
+// reTitleQualifier is a parenthesized qualifier in an article title, as in "John Smith (poet)"
+var reTitleQualifier = regexp.MustCompile(`\s*\([^)]*\)$`)
+
+// author is a catalog author facet to enrich
+type author struct {
+	Facet string
+	Count int // catalog records, if known
+}
+
+// matchesDates reports whether an article can be about the author of a facet: if the
+// facet gives a year of birth or death, the summary must mention it
+func matchesDates(facet string, article *Article) bool {
+	_, born, died := names.LifeDates(facet)
+
+	for _, year := range []int{born, died} {
+		if year > 0 && strings.Contains(article.Summary, strconv.Itoa(year)) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+// truncateBio shortens a summary to at most maxBioLength bytes, at the end of a sentence
+// where possible
+func truncateBio(summary string) string {
+	if len(summary) <= maxBioLength {
+		return summary
+	}
+
+	cut := summary[:maxBioLength]
+	if i := strings.LastIndex(cut, ". "); i > 0 {
+		return cut[:i+1]
+	}
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		return cut[:i] + "…"
+	}
+	return cut
+}
+
+// authorDocument builds the knowledge base document for an author facet from its article
+func authorDocument(a author, article *Article, maxWorks int) kb.Document {
+	name := reTitleQualifier.ReplaceAllString(article.Title, "")
+
+	works := article.Works
+	if len(works) > maxWorks {
+		works = works[:maxWorks]
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "# %s\n\n", name)
+	fmt.Fprintf(&text, "Catalog name: %s\n\n", a.Facet)
+	fmt.Fprintf(&text, "%s\n", article.Summary)
+	if len(works) > 0 {
+		text.WriteString("\n## Notable works\n\n")
+		for _, w := range works {
+			fmt.Fprintf(&text, "- %s\n", w)
+		}
+	}
+	if article.URL != "" {
+		fmt.Fprintf(&text, "\nSource: %s\n", article.URL)
+	}
+
+	metadata := map[string]interface{}{
+		providers.MetaAuthorFacetLabel: a.Facet,
+		providers.MetaAuthorName:       name,
+		providers.MetaAuthorBio:        truncateBio(article.Summary),
+	}
+	if len(works) > 0 {
+		metadata["notable_works"] = works
+	}
+	if article.URL != "" {
+		metadata["source_url"] = article.URL
+	}
+	if a.Count > 0 {
+		metadata["count"] = a.Count
+	}
+
+	return kb.Document{
+		Name:     kb.FileName(a.Facet),
+		Text:     text.String(),
+		Metadata: metadata,
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/uvalib/virgo4-suggestor-ws/kb"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+func TestMatchesDates(t *testing.T) {
+	tests := []struct {
+		facet   string
+		summary string
+		want    bool
+	}{
+		{"Twain, Mark, 1835-1910", "Samuel Langhorne Clemens (1835 – 1910) was an American writer", true},
+		{"Twain, Mark, 1835-1910", "Samuel Langhorne Clemens (born 1835) was an American writer", false},
+		{"Smith, John, 1950-", "John Smith (1580-1631) was an English explorer", false},
+		{"Smith, John", "John Smith (1580-1631) was an English explorer", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchesDates(tt.facet, &Article{Summary: tt.summary}); got != tt.want {
+			t.Errorf("matchesDates(%q, %q) = %v, want %v", tt.facet, tt.summary, got, tt.want)
+		}
+	}
+}
+
+// TestAuthorDocument writes a document and reads its metadata sidecar back, to check that
+// it carries what the service's author retriever needs
+func TestAuthorDocument(t *testing.T) {
+	article := &Article{
+		Title:   "Mark Twain (writer)",
+		URL:     "https://en.wikipedia.org/wiki/Mark_Twain",
+		Summary: strings.Repeat("Samuel Langhorne Clemens was an American writer. ", 30),
+		Works:   []string{"Roughing It", "The Gilded Age", "Adventures of Huckleberry Finn"},
+	}
+
+	doc := authorDocument(author{Facet: "Twain, Mark, 1835-1910", Count: 812}, article, 2)
+
+	dir := t.TempDir()
+	if err := kb.Write(dir, doc); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	docs, err := kb.ReadDir(dir)
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("ReadDir = %d documents, %v; want 1", len(docs), err)
+	}
+
+	got := docs[0]
+	if got.Name != "Twain_Mark_1835_1910" {
+		t.Errorf("document name = %q, want %q", got.Name, "Twain_Mark_1835_1910")
+	}
+
+	if err := providers.AuthorMetadata.Check(got.Metadata); err != nil {
+		t.Errorf("metadata %v does not satisfy the author retriever: %v", got.Metadata, err)
+	}
+
+	if got.Metadata[providers.MetaAuthorFacetLabel] != "Twain, Mark, 1835-1910" || got.Metadata[providers.MetaAuthorName] != "Mark Twain" {
+		t.Errorf("metadata facet %q, name %q; want the facet and the title without its qualifier", got.Metadata[providers.MetaAuthorFacetLabel], got.Metadata[providers.MetaAuthorName])
+	}
+
+	bio, _ := got.Metadata[providers.MetaAuthorBio].(string)
+	if len(bio) > maxBioLength || strings.HasSuffix(bio, ".") == false {
+		t.Errorf("bio of %d bytes ending %q; want at most %d, ending a sentence", len(bio), bio[len(bio)-10:], maxBioLength)
+	}
+
+	if works := got.Metadata["notable_works"]; reflect.DeepEqual(works, []interface{}{"Roughing It", "The Gilded Age"}) == false {
+		t.Errorf("notable_works = %v, want the first two", works)
+	}
+
+	if strings.Contains(got.Text, "Catalog name: Twain, Mark, 1835-1910") == false || strings.Contains(got.Text, "Adventures of Huckleberry Finn") == true {
+		t.Errorf("document text does not match its metadata:\n%s", got.Text)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uvalib/virgo4-suggestor-ws/kb"
+	"github.com/uvalib/virgo4-suggestor-ws/names"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// authorPageSize is the number of authors read from Solr per request
+const authorPageSize = 1000
+
+// enrichStats counts the outcomes of a run
+type enrichStats struct {
+	mu       sync.Mutex
+	written  int
+	existing int
+	cached   int
+	missing  int
+	mismatch int
+	failed   int
+}
+
+func (s *enrichStats) add(counter *int) {
+	s.mu.Lock()
+	*counter++
+	s.mu.Unlock()
+}
+
+func main() {
+	var solrHost, solrCore, authorsFile string
+	var sourceName, dumpFile, fixtureDir, lang, outDir string
+	var maxAuthors, minCount, maxWorks, retryDays, concurrency, delayMS int
+	var refresh bool
+	flag.StringVar(&solrHost, "solr", "", "Solr base URL, e.g. http://localhost:8983/solr")
+	flag.StringVar(&solrCore, "core", "autocomplete", "Solr core holding the author facets")
+	flag.StringVar(&authorsFile, "authors", "", "file of author facets, one per line, read instead of Solr")
+	flag.IntVar(&maxAuthors, "max", 1000, "maximum number of authors to read from Solr, most prolific first")
+	flag.IntVar(&minCount, "min-count", 2, "minimum catalog records of an author read from Solr")
+	flag.StringVar(&sourceName, "source", "wikipedia", "article source: wikipedia, dump or fixtures")
+	flag.StringVar(&dumpFile, "dump", "", "JSON lines dump file, for -source dump")
+	flag.StringVar(&fixtureDir, "fixtures", "", "fixture directory, for -source fixtures")
+	flag.StringVar(&lang, "lang", "en", "Wikipedia language, for -source wikipedia")
+	flag.IntVar(&delayMS, "delay", 200, "minimum milliseconds between Wikipedia requests")
+	flag.StringVar(&outDir, "out", "enriched", "output directory for knowledge base documents")
+	flag.IntVar(&maxWorks, "works", 15, "maximum notable works per author")
+	flag.IntVar(&retryDays, "retry-days", 30, "days before authors with no article are looked up again")
+	flag.IntVar(&concurrency, "concurrency", 2, "number of authors looked up at once")
+	flag.BoolVar(&refresh, "refresh", false, "rewrite existing documents and ignore the negative cache")
+	flag.Parse()
+
+	if solrHost == "" && authorsFile == "" {
+		log.Fatal("one of solr or authors is required")
+	}
+
+	var source Source
+	switch sourceName {
+	case "wikipedia":
+		source = newWikipediaSource(lang, 15*time.Second, time.Duration(delayMS)*time.Millisecond)
+	case "dump":
+		if dumpFile == "" {
+			log.Fatal("dump is required for the dump source")
+		}
+		d, err := newDumpSource(dumpFile)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		source = d
+	case "fixtures":
+		if fixtureDir == "" {
+			log.Fatal("fixtures is required for the fixtures source")
+		}
+		source = &fixtureSource{dir: fixtureDir}
+	default:
+		log.Fatalf("unknown source %s", sourceName)
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		log.Fatal(err.Error())
+	}
+
+	cache, err := loadNegativeCache(filepath.Join(outDir, negativeCacheFile), time.Duration(retryDays)*24*time.Hour)
+	if err != nil {
+		log.Fatalf("failed to read negative cache: %s", err.Error())
+	}
+
+	var authors []author
+	if authorsFile != "" {
+		authors, err = readAuthorsFile(authorsFile)
+	} else {
+		authors, err = readAuthorsSolr(kb.NewSolr(solrHost, solrCore, 30*time.Second), maxAuthors, minCount)
+	}
+	if err != nil {
+		log.Fatalf("failed to read authors: %s", err.Error())
+	}
+	log.Printf("[ENRICH] %d authors to enrich from %s into %s", len(authors), sourceName, outDir)
+
+	// several facets, such as "Twain, Mark" and "Twain, Mark, 1835-1910", can name the same
+	// person; each gets its own document, but the article is looked up once
+	var titles []string
+	byTitle := make(map[string][]author)
+	stats := &enrichStats{}
+
+	for _, a := range authors {
+		if refresh == false {
+			if _, err := os.Stat(kb.DocumentPath(outDir, kb.FileName(a.Facet))); err == nil {
+				stats.existing++
+				continue
+			}
+			if cache.has(a.Facet) == true {
+				stats.cached++
+				continue
+			}
+		}
+
+		title := names.Display(a.Facet)
+		if title == "" {
+			continue
+		}
+		if _, ok := byTitle[title]; ok == false {
+			titles = append(titles, title)
+		}
+		byTitle[title] = append(byTitle[title], a)
+	}
+
+	var wg sync.WaitGroup
+	var namesMu sync.Mutex
+	fileNames := make(map[string]string)
+	sem := make(chan struct{}, max(concurrency, 1))
+
+	for _, title := range titles {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(title string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			article, err := source.Lookup(title)
+			if err != nil {
+				log.Printf("[ENRICH] lookup of %s failed: %s", title, err.Error())
+				stats.add(&stats.failed)
+				return
+			}
+			if article == nil {
+				log.Printf("[ENRICH] no article for %s", title)
+				for _, a := range byTitle[title] {
+					cache.add(a.Facet, "no article")
+				}
+				stats.add(&stats.missing)
+				return
+			}
+			if normalizeTitle(article.Title) != normalizeTitle(title) {
+				log.Printf("[ENRICH] %s redirects to %s", title, article.Title)
+			}
+
+			for _, a := range byTitle[title] {
+				if matchesDates(a.Facet, article) == false {
+					log.Printf("[ENRICH] %s does not match the dates of %s", article.Title, a.Facet)
+					cache.add(a.Facet, fmt.Sprintf("dates do not match %s", article.Title))
+					stats.add(&stats.mismatch)
+					continue
+				}
+
+				doc := authorDocument(a, article, maxWorks)
+				if err := providers.AuthorMetadata.Check(doc.Metadata); err != nil {
+					log.Printf("[ENRICH] skipping %s: %s", a.Facet, err.Error())
+					stats.add(&stats.failed)
+					continue
+				}
+
+				namesMu.Lock()
+				other, taken := fileNames[doc.Name]
+				if taken == false {
+					fileNames[doc.Name] = a.Facet
+				}
+				namesMu.Unlock()
+				if taken == true {
+					log.Printf("[ENRICH] skipping %s: its file name %s is taken by %s", a.Facet, doc.Name, other)
+					stats.add(&stats.failed)
+					continue
+				}
+
+				if err := kb.Write(outDir, doc); err != nil {
+					log.Printf("[ENRICH] failed to write %s: %s", doc.Name, err.Error())
+					stats.add(&stats.failed)
+					continue
+				}
+				cache.remove(a.Facet)
+				stats.add(&stats.written)
+			}
+		}(title)
+	}
+	wg.Wait()
+
+	if err := cache.save(); err != nil {
+		log.Printf("[ENRICH] failed to save negative cache: %s", err.Error())
+	}
+
+	log.Printf("[ENRICH] written: %d, already written: %d, negatively cached: %d, no article: %d, dates mismatched: %d, failed: %d",
+		stats.written, stats.existing, stats.cached, stats.missing, stats.mismatch, stats.failed)
+}
+
+// readAuthorsFile reads author facets, one per line; blank lines and # comments are skipped
+func readAuthorsFile(path string) ([]author, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var authors []author
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") == true {
+			continue
+		}
+		authors = append(authors, author{Facet: line})
+	}
+
+	return authors, scanner.Err()
+}
+
+// readAuthorsSolr reads the most prolific author facets from the autocomplete core
+func readAuthorsSolr(solr *kb.Solr, maxAuthors int, minCount int) ([]author, error) {
+	params := map[string]interface{}{
+		"q":    "*:*",
+		"fq":   []string{"type:author", fmt.Sprintf("count:[%d TO *]", minCount)},
+		"fl":   "phrase,count",
+		"sort": "count desc",
+	}
+
+	var authors []author
+	err := solr.Page(params, authorPageSize, maxAuthors, func(docs []map[string]interface{}) error {
+		for _, doc := range docs {
+			if facet := kb.String(doc, "phrase"); facet != "" {
+				authors = append(authors, author{Facet: facet, Count: kb.Int(doc, "count")})
+			}
+		}
+		return nil
+	})
+
+	return authors, err
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxRedirects is the longest chain of redirects followed to reach an article
+const maxRedirects = 5
+
+// Article is what a source knows about an author
+type Article struct {
+	Title   string   // title after any redirects
+	URL     string   // where the article can be read
+	Summary string   // lead paragraph(s)
+	Works   []string // notable works, as listed by the article
+}
+
+// Source looks up author articles by title
+type Source interface {
+	// Lookup returns the article with a title, following redirects, or nil if there is none
+	Lookup(title string) (*Article, error)
+}
+
+// record is an article, or a redirect to one, in a dump or fixture file
+type record struct {
+	Title    string   `json:"title"`
+	Redirect string   `json:"redirect,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Summary  string   `json:"summary,omitempty"`
+	Works    []string `json:"works,omitempty"`
+}
+
+// normalizeTitle gives the form in which titles are compared: underscores are spaces,
+// and case does not matter
+func normalizeTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(title, "_", " ")), " "))
+}
+
+// resolve follows redirect records from a title to an article
+func resolve(title string, get func(title string) (*record, error)) (*Article, error) {
+	seen := make(map[string]bool)
+
+	for i := 0; i <= maxRedirects; i++ {
+		rec, err := get(title)
+		if err != nil || rec == nil {
+			return nil, err
+		}
+
+		if rec.Redirect == "" {
+			if rec.Title == "" {
+				rec.Title = title
+			}
+			return &Article{Title: rec.Title, URL: rec.URL, Summary: rec.Summary, Works: rec.Works}, nil
+		}
+
+		seen[normalizeTitle(title)] = true
+		title = rec.Redirect
+		if seen[normalizeTitle(title)] == true {
+			return nil, fmt.Errorf("redirect loop at %s", title)
+		}
+	}
+
+	return nil, fmt.Errorf("more than %d redirects from %s", maxRedirects, title)
+}
+
+// dumpSource reads articles from a local dump: a file of JSON records, one per line
+type dumpSource struct {
+	records map[string]*record
+}
+
+// newDumpSource loads a dump file into memory
+func newDumpSource(path string) (*dumpSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	src := &dumpSource{records: make(map[string]*record)}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if rec.Title == "" {
+			return nil, fmt.Errorf("%s:%d: record has no title", path, line)
+		}
+		src.records[normalizeTitle(rec.Title)] = &rec
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return src, nil
+}
+
+// Lookup returns the article with a title from the dump
+func (d *dumpSource) Lookup(title string) (*Article, error) {
+	return resolve(title, func(title string) (*record, error) {
+		return d.records[normalizeTitle(title)], nil
+	})
+}
+
+// fixtureSource reads articles from a directory holding one JSON record per file, named
+// for its title with underscores for spaces (e.g. "Mark_Twain.json"), for use offline
+type fixtureSource struct {
+	dir string
+}
+
+// fixtureEscaper escapes the characters that would take a fixture file name out of the
+// fixture directory, as in a URL
+var fixtureEscaper = strings.NewReplacer("%", "%25", "/", "%2F", "\\", "%5C")
+
+// fixtureFileName returns the file a title is read from, so that "AC/DC" is read from
+// "AC%2FDC.json" rather than from a subdirectory
+func fixtureFileName(title string) string {
+	return fixtureEscaper.Replace(strings.ReplaceAll(strings.TrimSpace(title), " ", "_")) + ".json"
+}
+
+// Lookup returns the article with a title from the fixture directory
+func (f *fixtureSource) Lookup(title string) (*Article, error) {
+	return resolve(title, func(title string) (*record, error) {
+		path := filepath.Join(f.dir, fixtureFileName(title))
+
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) == true {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var rec record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &rec, nil
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordGetter serves records from a map, by normalized title
+func recordGetter(records ...record) func(title string) (*record, error) {
+	byTitle := make(map[string]*record)
+	for i := range records {
+		byTitle[normalizeTitle(records[i].Title)] = &records[i]
+	}
+	return func(title string) (*record, error) {
+		return byTitle[normalizeTitle(title)], nil
+	}
+}
+
+func TestResolve(t *testing.T) {
+	twain := record{Title: "Mark Twain", URL: "https://en.wikipedia.org/wiki/Mark_Twain", Summary: "American writer", Works: []string{"Roughing It"}}
+
+	tests := []struct {
+		name    string
+		records []record
+		title   string
+		want    *Article
+		err     string
+	}{
+		{"article", []record{twain}, "mark_twain", &Article{Title: "Mark Twain", URL: twain.URL, Summary: twain.Summary, Works: twain.Works}, ""},
+		{
+			"redirect chain",
+			[]record{twain, {Title: "Samuel Clemens", Redirect: "Samuel Langhorne Clemens"}, {Title: "Samuel Langhorne Clemens", Redirect: "Mark Twain"}},
+			"Samuel Clemens", &Article{Title: "Mark Twain", URL: twain.URL, Summary: twain.Summary, Works: twain.Works}, "",
+		},
+		{"untitled article", []record{{Title: "", Summary: "American writer"}}, "", &Article{Title: "", Summary: "American writer"}, ""},
+		{"missing", []record{twain}, "Bret Harte", nil, ""},
+		{"redirect to nothing", []record{{Title: "Clemens", Redirect: "Nobody"}}, "Clemens", nil, ""},
+		{"redirect loop", []record{{Title: "A", Redirect: "B"}, {Title: "B", Redirect: "a"}}, "A", nil, "redirect loop at a"},
+		{"redirect to itself", []record{{Title: "A", Redirect: "A"}}, "A", nil, "redirect loop at A"},
+		{
+			"too many redirects",
+			[]record{{Title: "1", Redirect: "2"}, {Title: "2", Redirect: "3"}, {Title: "3", Redirect: "4"}, {Title: "4", Redirect: "5"}, {Title: "5", Redirect: "6"}, {Title: "6", Redirect: "7"}, twain, {Title: "7", Redirect: "Mark Twain"}},
+			"1", nil, "more than 5 redirects",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := resolve(tt.title, recordGetter(tt.records...))
+
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: resolve(%q) failed: %v", tt.name, tt.title, err)
+			continue
+		}
+		if tt.err != "" && (err == nil || strings.Contains(err.Error(), tt.err) == false) {
+			t.Errorf("%s: resolve(%q) error = %v, want %q", tt.name, tt.title, err, tt.err)
+			continue
+		}
+		if reflect.DeepEqual(got, tt.want) == false {
+			t.Errorf("%s: resolve(%q) = %+v, want %+v", tt.name, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestDumpSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.jsonl")
+	dump := `{"title": "Mark Twain", "summary": "American writer"}
+
+{"title": "Samuel_Clemens", "redirect": "Mark Twain"}
+`
+	if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := newDumpSource(path)
+	if err != nil {
+		t.Fatalf("newDumpSource failed: %v", err)
+	}
+
+	article, err := src.Lookup("samuel clemens")
+	if err != nil || article == nil || article.Title != "Mark Twain" {
+		t.Errorf("Lookup(%q) = %+v, %v; want Mark Twain", "samuel clemens", article, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"summary": "no title"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newDumpSource(path); err == nil || strings.Contains(err.Error(), ":1: record has no title") == false {
+		t.Errorf("newDumpSource with an untitled record: error = %v, want one naming line 1", err)
+	}
+}
+
+func TestFixtureSource(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "fixtures")
+
+	files := map[string]string{
+		"Mark_Twain.json":     `{"title": "Mark Twain", "summary": "American writer"}`,
+		"Samuel_Clemens.json": `{"title": "Samuel Clemens", "redirect": "Mark Twain"}`,
+		"AC%2FDC.json":        `{"title": "AC/DC", "summary": "Australian band"}`,
+		"AC/DC.json":          `{"title": "AC/DC", "summary": "the wrong file"}`,
+		"Broken.json":         `{"title": `,
+		"../Outside.json":     `{"title": "Outside", "summary": "outside the fixtures"}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := &fixtureSource{dir: dir}
+
+	tests := []struct {
+		title   string
+		summary string // empty if there is no article
+		err     bool
+	}{
+		{"Samuel Clemens", "American writer", false},
+		{" Mark Twain ", "American writer", false},
+		{"AC/DC", "Australian band", false},
+		{"../Outside", "", false},
+		{"Bret Harte", "", false},
+		{"Broken", "", true},
+	}
+
+	for _, tt := range tests {
+		article, err := src.Lookup(tt.title)
+		if (err != nil) != tt.err {
+			t.Errorf("Lookup(%q) error = %v, want error %v", tt.title, err, tt.err)
+			continue
+		}
+
+		summary := ""
+		if article != nil {
+			summary = article.Summary
+		}
+		if summary != tt.summary {
+			t.Errorf("Lookup(%q) summary = %q, want %q", tt.title, summary, tt.summary)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// wikipediaUserAgent identifies the tool to Wikipedia, as its API policy asks
+const wikipediaUserAgent = "virgo4-suggestor-enrich/1.0 (University of Virginia Library)"
+
+// worksSections are the section headings, lowercased, under which articles list works
+var worksSections = []string{"works", "bibliography", "selected works", "selected bibliography", "notable works", "publications", "writings"}
+
+var (
+	reWikiRefs      = regexp.MustCompile(`(?s)<ref[^>]*/>|<ref[^>]*>.*?</ref>`)
+	reWikiTemplate  = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+	reWikiLink      = regexp.MustCompile(`\[\[(?:[^|\]]*\|)?([^\]]*)\]\]`)
+	reWikiExtLink   = regexp.MustCompile(`\[https?://\S+\s*([^\]]*)\]`)
+	reWikiTag       = regexp.MustCompile(`<[^>]+>`)
+	reWikiEmphasis  = regexp.MustCompile(`'{2,}`)
+	reWikiListStart = regexp.MustCompile(`^[*#]+\s*`)
+)
+
+// wikipediaSource looks articles up with the Wikipedia APIs: the REST summary of an
+// article, and the list items of its works section
+type wikipediaSource struct {
+	restURL string // e.g. https://en.wikipedia.org/api/rest_v1
+	apiURL  string // e.g. https://en.wikipedia.org/w/api.php
+	client  *http.Client
+	delay   time.Duration // minimum time between requests
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// wikipediaSummary is the part of a REST summary response used
+type wikipediaSummary struct {
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Extract     string `json:"extract"`
+	ContentURLs struct {
+		Desktop struct {
+			Page string `json:"page"`
+		} `json:"desktop"`
+	} `json:"content_urls"`
+}
+
+// wikipediaParse is the part of an action=parse response used (formatversion=2)
+type wikipediaParse struct {
+	Parse struct {
+		Sections []struct {
+			Line  string `json:"line"`
+			Index string `json:"index"`
+		} `json:"sections"`
+		Wikitext string `json:"wikitext"`
+	} `json:"parse"`
+	Error struct {
+		Code string `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// newWikipediaSource returns a source for the Wikipedia of a language
+func newWikipediaSource(lang string, timeout time.Duration, delay time.Duration) *wikipediaSource {
+	base := fmt.Sprintf("https://%s.wikipedia.org", lang)
+	return &wikipediaSource{
+		restURL: base + "/api/rest_v1",
+		apiURL:  base + "/w/api.php",
+		client:  &http.Client{Timeout: timeout},
+		delay:   delay,
+	}
+}
+
+// get fetches a URL into v, keeping to the request delay.  It reports false if there is
+// nothing at the URL.
+func (w *wikipediaSource) get(target string, v interface{}) (bool, error) {
+	w.mu.Lock()
+	if wait := w.delay - time.Since(w.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	w.last = time.Now()
+	w.mu.Unlock()
+
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("User-Agent", wikipediaUserAgent)
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("%s returned %d", target, res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("failed to decode %s: %w", target, err)
+	}
+
+	return true, nil
+}
+
+// Lookup returns the article with a title.  The summary endpoint follows redirects, and
+// the title it reports is the one the works are read from.  Disambiguation pages are not
+// articles about an author.
+func (w *wikipediaSource) Lookup(title string) (*Article, error) {
+	var summary wikipediaSummary
+
+	target := fmt.Sprintf("%s/page/summary/%s?redirect=true", w.restURL, url.PathEscape(strings.ReplaceAll(title, " ", "_")))
+	found, err := w.get(target, &summary)
+	if err != nil || found == false {
+		return nil, err
+	}
+
+	if summary.Type == "disambiguation" || strings.TrimSpace(summary.Extract) == "" {
+		return nil, nil
+	}
+
+	works, err := w.works(summary.Title)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read works of %s: %w", summary.Title, err)
+	}
+
+	return &Article{
+		Title:   summary.Title,
+		URL:     summary.ContentURLs.Desktop.Page,
+		Summary: strings.TrimSpace(summary.Extract),
+		Works:   works,
+	}, nil
+}
+
+// works returns the list items of the first works section of an article
+func (w *wikipediaSource) works(title string) ([]string, error) {
+	params := url.Values{
+		"action":        {"parse"},
+		"page":          {title},
+		"prop":          {"sections"},
+		"redirects":     {"1"},
+		"format":        {"json"},
+		"formatversion": {"2"},
+	}
+
+	var sections wikipediaParse
+	if _, err := w.get(w.apiURL+"?"+params.Encode(), &sections); err != nil {
+		return nil, err
+	}
+	if sections.Error.Code != "" {
+		return nil, fmt.Errorf("%s: %s", sections.Error.Code, sections.Error.Info)
+	}
+
+	index := ""
+	for _, s := range sections.Parse.Sections {
+		heading := strings.ToLower(strings.TrimSpace(reWikiTag.ReplaceAllString(s.Line, "")))
+		for _, name := range worksSections {
+			if heading == name {
+				index = s.Index
+				break
+			}
+		}
+		if index != "" {
+			break
+		}
+	}
+
+	if index == "" {
+		return nil, nil
+	}
+
+	params.Set("prop", "wikitext")
+	params.Set("section", index)
+
+	var section wikipediaParse
+	if _, err := w.get(w.apiURL+"?"+params.Encode(), &section); err != nil {
+		return nil, err
+	}
+
+	return wikitextListItems(section.Parse.Wikitext), nil
+}
+
+// wikitextListItems returns the text of the list items in wikitext, with references,
+// templates, links and formatting stripped
+func wikitextListItems(wikitext string) []string {
+	wikitext = reWikiRefs.ReplaceAllString(wikitext, "")
+
+	var items []string
+	for _, line := range strings.Split(wikitext, "\n") {
+		if reWikiListStart.MatchString(line) == false {
+			continue
+		}
+
+		text := reWikiListStart.ReplaceAllString(line, "")
+		for reWikiTemplate.MatchString(text) == true {
+			text = reWikiTemplate.ReplaceAllString(text, "")
+		}
+		text = reWikiLink.ReplaceAllString(text, "$1")
+		text = reWikiExtLink.ReplaceAllString(text, "$1")
+		text = reWikiTag.ReplaceAllString(text, "")
+		text = reWikiEmphasis.ReplaceAllString(text, "")
+		text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+
+		if len(text) > 3 {
+			items = append(items, text)
+		}
+	}
+
+	return items
+}
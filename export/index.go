@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+
+	"github.com/uvalib/virgo4-suggestor-ws/kb"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// localIndex gathers the documents of one kind for a local index, which the service can
+// search instead of a Bedrock knowledge base
+type localIndex struct {
+	dir      string
+	kind     string
+	embedder providers.Embedder
+	docs     []providers.LocalDocument
+}
+
+// add embeds a document's text and adds it to the index
+func (i *localIndex) add(doc kb.Document) error {
+	vec, err := i.embedder.Embed(doc.Text)
+	if err != nil {
+		return err
+	}
+
+	i.docs = append(i.docs, providers.LocalDocument{Name: doc.Name, Vector: vec, Metadata: doc.Metadata})
+	return nil
+}
+
+// write replaces the index's documents of this kind with those added
+func (i *localIndex) write() error {
+	if err := providers.WriteLocalIndex(i.dir, i.kind, i.embedder, i.docs); err != nil {
+		return err
+	}
+
+	log.Printf("[INDEX] wrote %d %s documents to %s (%s embedder, %d dimensions)", len(i.docs), i.kind, i.dir, i.embedder.Name(), i.embedder.Dimensions())
+	return nil
+}
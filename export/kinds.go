@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/uvalib/virgo4-suggestor-ws/names"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// exportKind describes how the Solr records of one knowledge base become its documents
+type exportKind struct {
+	core     string            // default Solr core; empty if it must be given
+	fq       []string          // default filter queries
+	sort     string            // order in which records are exported
+	fields   map[string]string // default metadata key -> Solr field
+	nameKey  string            // metadata key whose value names the document file
+	titleKey string            // metadata key whose value heads the document
+	bodyKey  string            // metadata key whose value is the document's text, if any
+	schema   providers.MetadataSchema
+	derive   func(metadata map[string]interface{}) // fills in metadata not read from Solr
+}
+
+// exportKinds are the knowledge bases documents can be exported for, by the names their
+// local index files have.  Book and image records
+// come from the catalog; which core and fields hold them differs between installations, so
+// the core must be given and the fields may be remapped.
+var exportKinds = map[string]exportKind{
+	providers.LocalKindAuthor: {
+		core: "autocomplete",
+		fq:   []string{"type:author"},
+		sort: "count desc",
+		fields: map[string]string{
+			providers.MetaAuthorFacetLabel: "phrase",
+			"count":                        "count",
+		},
+		nameKey:  providers.MetaAuthorFacetLabel,
+		titleKey: providers.MetaAuthorName,
+		bodyKey:  providers.MetaAuthorBio,
+		schema:   providers.AuthorMetadata,
+		derive: func(metadata map[string]interface{}) {
+			if facet, ok := metadata[providers.MetaAuthorFacetLabel].(string); ok == true {
+				metadata[providers.MetaAuthorName] = names.Display(facet)
+			}
+		},
+	},
+	providers.LocalKindBook: {
+		sort: "id asc",
+		fields: map[string]string{
+			providers.MetaBookID:      "id",
+			providers.MetaBookTitle:   "title_a",
+			providers.MetaBookAuthors: "author_a",
+			providers.MetaBookType:    "format_f",
+		},
+		nameKey:  providers.MetaBookID,
+		titleKey: providers.MetaBookTitle,
+		bodyKey:  providers.MetaBookDescription,
+		schema:   providers.BookMetadata,
+	},
+	providers.LocalKindImage: {
+		sort: "id asc",
+		fields: map[string]string{
+			providers.MetaImageID:            "id",
+			providers.MetaImageTitleAlt:      "title_a",
+			providers.MetaImageCollectionAlt: "digital_collection_a",
+		},
+		nameKey:  providers.MetaImageID,
+		titleKey: providers.MetaImageTitleAlt,
+		schema:   providers.ImageMetadata,
+	},
+}
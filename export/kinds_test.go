@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/uvalib/virgo4-suggestor-ws/kb"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// exportExtraKeys are metadata keys exported for information that no retriever reads
+var exportExtraKeys = map[string]bool{"count": true}
+
+// sampleRecord returns a Solr record with a value for every field a kind exports
+func sampleRecord(kind exportKind) map[string]interface{} {
+	doc := make(map[string]interface{})
+	for key, field := range kind.fields {
+		switch {
+		case kind.schema.Keys[key] == providers.MetaKindList:
+			doc[field] = []interface{}{"Twain, Mark, 1835-1910", "Warner, Charles Dudley, 1829-1900"}
+		case kind.schema.Keys[key] == providers.MetaKindNumber || exportExtraKeys[key] == true:
+			doc[field] = float64(812)
+		default:
+			doc[field] = []interface{}{"sample " + field}
+		}
+	}
+	return doc
+}
+
+// TestExportKinds checks that each kind exports under the keys its retriever reads, and that
+// the metadata sidecar written for a record passes the retriever's schema once read back
+func TestExportKinds(t *testing.T) {
+	var kindNames []string
+	for name := range exportKinds {
+		kindNames = append(kindNames, name)
+	}
+	sort.Strings(kindNames)
+
+	for _, name := range kindNames {
+		kind := exportKinds[name]
+
+		for key := range kind.fields {
+			if _, ok := kind.schema.Keys[key]; ok == false && exportExtraKeys[key] == false {
+				t.Errorf("%s: field %q is exported under a key the retriever does not read", name, key)
+			}
+		}
+		for _, key := range []string{kind.nameKey, kind.titleKey, kind.bodyKey} {
+			if _, ok := kind.schema.Keys[key]; key != "" && ok == false {
+				t.Errorf("%s: document key %q is not read by the retriever", name, key)
+			}
+		}
+
+		dir := t.TempDir()
+
+		doc := exportDocument(kind, kind.fields, sampleRecord(kind))
+		if doc.Name == "" {
+			t.Errorf("%s: document has no name", name)
+			continue
+		}
+
+		if err := kb.Write(dir, doc); err != nil {
+			t.Fatalf("%s: Write failed: %v", name, err)
+		}
+
+		if checkDocuments(dir, kind.schema, nil) == false {
+			t.Errorf("%s: exported document fails the retriever's schema: %v", name, kind.schema.Check(doc.Metadata))
+		}
+
+		docs, err := kb.ReadDir(dir)
+		if err != nil || len(docs) != 1 {
+			t.Fatalf("%s: ReadDir = %d documents, %v; want 1", name, len(docs), err)
+		}
+		for key := range kind.fields {
+			if _, ok := docs[0].Metadata[key]; ok == false {
+				t.Errorf("%s: metadata read back has no %q", name, key)
+			}
+		}
+
+		// a record without the fields that identify it is rejected
+		empty := exportDocument(kind, kind.fields, map[string]interface{}{})
+		if err := kind.schema.Check(empty.Metadata); err == nil {
+			t.Errorf("%s: a document from an empty record passes the schema", name)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uvalib/virgo4-suggestor-ws/kb"
+	"github.com/uvalib/virgo4-suggestor-ws/providers"
+)
+
+// exportPageSize is the number of records read from Solr per request
+const exportPageSize = 1000
+
+// listFlag collects the values of a flag given more than once
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(val string) error {
+	*l = append(*l, val)
+	return nil
+}
+
+func main() {
+	var kindName, solrHost, solrCore, query, outDir, checkDir, indexDir, embedderName string
+	var maxRecords, dimensions int
+	var fq, fieldMap listFlag
+	flag.StringVar(&kindName, "kind", "", "knowledge base to export for: author, book or image")
+	flag.StringVar(&solrHost, "solr", "", "Solr base URL, e.g. http://localhost:8983/solr")
+	flag.StringVar(&solrCore, "core", "", "Solr core to export from (authors default to autocomplete)")
+	flag.StringVar(&query, "q", "*:*", "Solr query selecting the records")
+	flag.Var(&fq, "fq", "Solr filter query; may be repeated, and replaces the kind's default filters")
+	flag.Var(&fieldMap, "map", "metadata key to Solr field, as key=field; may be repeated")
+	flag.IntVar(&maxRecords, "max", 0, "maximum number of records to export (0 for all)")
+	flag.StringVar(&outDir, "out", "", "output directory (default kb-{kind})")
+	flag.StringVar(&checkDir, "check", "", "check the documents in this directory instead of exporting")
+	flag.StringVar(&indexDir, "index", "", "also write the documents to a local index in this directory")
+	flag.StringVar(&embedderName, "embedder", "hash", "embedder for the local index")
+	flag.IntVar(&dimensions, "dimensions", 0, "embedding dimensions for the local index (0 for the embedder default)")
+	flag.Parse()
+
+	kind, ok := exportKinds[kindName]
+	if ok == false {
+		log.Fatal("kind must be author, book or image")
+	}
+
+	var index *localIndex
+	if indexDir != "" {
+		embedder, err := providers.NewEmbedder(embedderName, dimensions)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		index = &localIndex{dir: indexDir, kind: kindName, embedder: embedder}
+	}
+
+	if checkDir != "" {
+		passed := checkDocuments(checkDir, kind.schema, index)
+		if index != nil {
+			if err := index.write(); err != nil {
+				log.Fatalf("failed to write local index: %s", err.Error())
+			}
+		}
+		if passed == false {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if solrHost == "" {
+		log.Fatal("solr is required")
+	}
+	if solrCore == "" {
+		solrCore = kind.core
+	}
+	if solrCore == "" {
+		log.Fatalf("core is required for %s records", kindName)
+	}
+	if len(fq) > 0 {
+		kind.fq = fq
+	}
+	if outDir == "" {
+		outDir = "kb-" + kindName
+	}
+
+	fields := make(map[string]string)
+	for key, field := range kind.fields {
+		fields[key] = field
+	}
+	for _, m := range fieldMap {
+		key, field, found := strings.Cut(m, "=")
+		if found == false || key == "" {
+			log.Fatalf("invalid map %s; expected key=field", m)
+		}
+		if field == "" {
+			delete(fields, key)
+			continue
+		}
+		fields[key] = field
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		log.Fatal(err.Error())
+	}
+
+	var fl []string
+	requested := make(map[string]bool)
+	for _, field := range fields {
+		if requested[field] == false {
+			requested[field] = true
+			fl = append(fl, field)
+		}
+	}
+	sort.Strings(fl)
+
+	params := map[string]interface{}{
+		"q":  query,
+		"fl": strings.Join(fl, ","),
+	}
+	if len(kind.fq) > 0 {
+		params["fq"] = kind.fq
+	}
+	if kind.sort != "" {
+		params["sort"] = kind.sort
+	}
+
+	solr := kb.NewSolr(solrHost, solrCore, 60*time.Second)
+	written, invalid := 0, 0
+	seen := make(map[string]bool)
+
+	err := solr.Page(params, exportPageSize, maxRecords, func(docs []map[string]interface{}) error {
+		for _, doc := range docs {
+			out := exportDocument(kind, fields, doc)
+
+			if err := kind.schema.Check(out.Metadata); err != nil {
+				log.Printf("[EXPORT] skipping record %s: %s", out.Name, err.Error())
+				invalid++
+				continue
+			}
+			if seen[out.Name] == true {
+				log.Printf("[EXPORT] skipping record %s: its file name is already taken", out.Name)
+				invalid++
+				continue
+			}
+			seen[out.Name] = true
+
+			if err := kb.Write(outDir, out); err != nil {
+				return err
+			}
+			if index != nil {
+				if err := index.add(out); err != nil {
+					return err
+				}
+			}
+			written++
+		}
+		log.Printf("[EXPORT] %d %s documents written", written, kindName)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("export failed: %s", err.Error())
+	}
+
+	if index != nil {
+		if err := index.write(); err != nil {
+			log.Fatalf("failed to write local index: %s", err.Error())
+		}
+	}
+
+	log.Printf("[EXPORT] wrote %d %s documents to %s; %d records skipped", written, kindName, outDir, invalid)
+}
+
+// exportDocument builds the knowledge base document for a Solr record: its metadata from
+// the mapped fields, and its text from the metadata
+func exportDocument(kind exportKind, fields map[string]string, doc map[string]interface{}) kb.Document {
+	metadata := make(map[string]interface{})
+
+	for key, field := range fields {
+		switch kind.schema.Keys[key] {
+		case providers.MetaKindList:
+			if list := kb.Strings(doc, field); len(list) > 0 {
+				metadata[key] = list
+			}
+		case providers.MetaKindNumber:
+			if n, ok := doc[field].(float64); ok == true {
+				metadata[key] = n
+			}
+		default:
+			if n, ok := doc[field].(float64); ok == true {
+				metadata[key] = n
+			} else if s := strings.TrimSpace(kb.String(doc, field)); s != "" {
+				metadata[key] = s
+			}
+		}
+	}
+
+	if kind.derive != nil {
+		kind.derive(metadata)
+	}
+
+	name, _ := metadata[kind.nameKey].(string)
+	title, _ := metadata[kind.titleKey].(string)
+	if title == "" {
+		title = name
+	}
+
+	var keys []string
+	for key := range metadata {
+		if key != kind.titleKey && key != kind.bodyKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "# %s\n\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(&text, "%s: %s\n", metadataLabel(key), metadataText(metadata[key]))
+	}
+	if body, _ := metadata[kind.bodyKey].(string); body != "" {
+		fmt.Fprintf(&text, "\n%s\n", body)
+	}
+
+	return kb.Document{
+		Name:     kb.FileName(name),
+		Text:     text.String(),
+		Metadata: metadata,
+	}
+}
+
+// metadataLabels are the labels in document text of keys that do not read well as words
+var metadataLabels = map[string]string{
+	providers.MetaAuthorFacetLabel: "Catalog name",
+	providers.MetaImageIIIFID:      "IIIF ID",
+	"id":                           "ID",
+}
+
+// metadataLabel turns a metadata key such as "digital_collection_a" into a label for the
+// document text, "Digital collection"
+func metadataLabel(key string) string {
+	if label, ok := metadataLabels[key]; ok == true {
+		return label
+	}
+	label := strings.ReplaceAll(strings.TrimSuffix(key, "_a"), "_", " ")
+	if label == "" {
+		return key
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// metadataText formats a metadata value for the document text
+func metadataText(val interface{}) string {
+	switch v := val.(type) {
+	case []string:
+		return strings.Join(v, "; ")
+	case float64:
+		return fmt.Sprintf("%v", v)
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// checkDocuments checks the metadata of the documents in a directory against a retriever's
+// schema, reporting whether all of them pass.  Those that pass are added to the index, if any.
+func checkDocuments(dir string, schema providers.MetadataSchema, index *localIndex) bool {
+	docs, err := kb.ReadDir(dir)
+	if err != nil {
+		log.Fatalf("failed to read %s: %s", dir, err.Error())
+	}
+
+	failed := 0
+	for _, doc := range docs {
+		if doc.Metadata == nil {
+			log.Printf("[CHECK] %s: no metadata sidecar", doc.Name)
+			failed++
+			continue
+		}
+		if err := schema.Check(doc.Metadata); err != nil {
+			log.Printf("[CHECK] %s: %s", doc.Name, err.Error())
+			failed++
+			continue
+		}
+		if index != nil {
+			if err := index.add(doc); err != nil {
+				log.Fatalf("failed to index %s: %s", doc.Name, err.Error())
+			}
+		}
+	}
+
+	log.Printf("[CHECK] %d documents checked in %s; %d failed", len(docs), dir, failed)
+
+	return failed == 0
+}
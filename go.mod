@@ -14,6 +14,7 @@ require (
 	github.com/uvalib/virgo4-jwt v1.3.4
 	github.com/uvalib/virgo4-parser v1.0.0
 	github.com/zsais/go-gin-prometheus v1.0.3
+	golang.org/x/text v0.36.0
 	gonum.org/v1/gonum v0.17.0
 )
 
@@ -71,6 +72,5 @@ require (
 	golang.org/x/exp v0.0.0-20260410095643-746e56fc9e2f // indirect
 	golang.org/x/net v0.53.0 // indirect
 	golang.org/x/sys v0.43.0 // indirect
-	golang.org/x/text v0.36.0 // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
 )
@@ -0,0 +1,114 @@
+// Package kb writes documents for the Bedrock knowledge base, and reads the catalog
+// records they are built from.  Each document is a markdown file with a metadata sidecar
+// holding the attributes the service's retrievers read back.
+package kb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// documentExt is the extension of knowledge base documents
+const documentExt = ".md"
+
+// metadataExt is appended to a document's file name to name its metadata sidecar
+const metadataExt = ".metadata.json"
+
+// Document is one knowledge base document
+type Document struct {
+	Name     string                 // file name, without extension
+	Text     string                 // markdown body
+	Metadata map[string]interface{} // metadata attributes: strings, numbers, booleans or string lists
+}
+
+// metadataSidecar is the layout of a Bedrock knowledge base metadata file
+type metadataSidecar struct {
+	MetadataAttributes map[string]interface{} `json:"metadataAttributes"`
+}
+
+// FileName reduces a string to a file name of letters, digits and underscores, so that
+// "Twain, Mark, 1835-1910" becomes "Twain_Mark_1835_1910"
+func FileName(s string) string {
+	var b strings.Builder
+	underscore := false
+
+	for _, r := range s {
+		if unicode.IsLetter(r) == true || unicode.IsDigit(r) == true {
+			b.WriteRune(r)
+			underscore = false
+			continue
+		}
+		if underscore == false {
+			b.WriteRune('_')
+			underscore = true
+		}
+	}
+
+	return strings.Trim(b.String(), "_")
+}
+
+// DocumentPath returns the path of a document of the given name in dir
+func DocumentPath(dir string, name string) string {
+	return filepath.Join(dir, name+documentExt)
+}
+
+// Write writes a document and its metadata sidecar to dir
+func Write(dir string, doc Document) error {
+	if doc.Name == "" {
+		return fmt.Errorf("document has no name")
+	}
+
+	path := DocumentPath(dir, doc.Name)
+
+	sidecar, err := json.MarshalIndent(metadataSidecar{MetadataAttributes: doc.Metadata}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata for %s: %w", doc.Name, err)
+	}
+
+	if err := os.WriteFile(path, []byte(doc.Text), 0644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path+metadataExt, sidecar, 0644)
+}
+
+// ReadDir reads the documents in dir, in file name order.  A document with no metadata
+// sidecar has nil metadata.
+func ReadDir(dir string) ([]Document, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+documentExt))
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	for _, path := range paths {
+		doc := Document{Name: strings.TrimSuffix(filepath.Base(path), documentExt)}
+
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		doc.Text = string(text)
+
+		data, err := os.ReadFile(path + metadataExt)
+		if err != nil && errors.Is(err, os.ErrNotExist) == false {
+			return nil, err
+		}
+		if err == nil {
+			var sidecar metadataSidecar
+			if err := json.Unmarshal(data, &sidecar); err != nil {
+				return nil, fmt.Errorf("%s: %w", path+metadataExt, err)
+			}
+			doc.Metadata = sidecar.MetadataAttributes
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
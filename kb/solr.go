@@ -0,0 +1,146 @@
+package kb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Solr is a minimal client for the JSON request API of one Solr core
+type Solr struct {
+	url    string
+	client *http.Client
+}
+
+// SolrResponse is the part of a Solr response the knowledge base tools read
+type SolrResponse struct {
+	ResponseHeader struct {
+		Status int `json:"status"`
+	} `json:"responseHeader"`
+	Response struct {
+		NumFound int                      `json:"numFound"`
+		Docs     []map[string]interface{} `json:"docs"`
+	} `json:"response"`
+	Error struct {
+		Msg  string `json:"msg"`
+		Code int    `json:"code"`
+	} `json:"error"`
+}
+
+// NewSolr returns a client for the select handler of a core, such as
+// "http://localhost:8983/solr" and "autocomplete"
+func NewSolr(host string, core string, timeout time.Duration) *Solr {
+	return &Solr{
+		url:    fmt.Sprintf("%s/%s/select", strings.TrimSuffix(host, "/"), core),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Select runs a request with the given parameters and returns the response
+func (s *Solr) Select(params map[string]interface{}) (*SolrResponse, error) {
+	body, err := json.Marshal(map[string]interface{}{"params": params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Solr request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", s.url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive Solr response: %w", err)
+	}
+	defer res.Body.Close()
+
+	var solrRes SolrResponse
+	if err := json.NewDecoder(res.Body).Decode(&solrRes); err != nil {
+		return nil, fmt.Errorf("failed to decode Solr response: %w", err)
+	}
+
+	if solrRes.ResponseHeader.Status != 0 || res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%d - %s", solrRes.Error.Code, solrRes.Error.Msg)
+	}
+
+	return &solrRes, nil
+}
+
+// Page runs a request page by page, handing each page's documents to fn, until max
+// documents (all of them, if max is 0) have been read
+func (s *Solr) Page(params map[string]interface{}, pageSize int, max int, fn func(docs []map[string]interface{}) error) error {
+	read := 0
+
+	for max == 0 || read < max {
+		rows := pageSize
+		if max > 0 {
+			rows = min(rows, max-read)
+		}
+
+		page := make(map[string]interface{}, len(params)+2)
+		for k, v := range params {
+			page[k] = v
+		}
+		page["start"] = read
+		page["rows"] = rows
+
+		res, err := s.Select(page)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(res.Response.Docs); err != nil {
+			return err
+		}
+
+		read += len(res.Response.Docs)
+		if len(res.Response.Docs) == 0 || read >= res.Response.NumFound {
+			break
+		}
+	}
+
+	return nil
+}
+
+// String returns a document field as a string; multi-valued fields give their first value
+func String(doc map[string]interface{}, field string) string {
+	switch v := doc[field].(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			s, _ := v[0].(string)
+			return s
+		}
+	case float64:
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+// Strings returns a document field as a list of strings
+func Strings(doc map[string]interface{}, field string) []string {
+	switch v := doc[field].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok == true && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// Int returns a numeric document field as an int
+func Int(doc map[string]interface{}, field string) int {
+	f, _ := doc[field].(float64)
+	return int(f)
+}
@@ -0,0 +1,59 @@
+package names
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reLifeDates = regexp.MustCompile(`,\s*([^,]*\d{3,4}[^,]*)$`)
+	reYear      = regexp.MustCompile(`\d{3,4}`)
+	reFacetRole = regexp.MustCompile(`\s*\(.*?\)`)
+)
+
+// LifeDates extracts the life dates from an author facet such as "Twain, Mark, 1835-1910",
+// "Smith, John, 1950-", "Smith, John, d. 1850" or "Smith, John, -1850", returning them as
+// given along with the years of birth and death.  Dates that are not life dates, such as
+// "fl. 1850", and dates B.C. are returned as given but not parsed.
+func LifeDates(facet string) (string, int, int) {
+	m := reLifeDates.FindStringSubmatch(strings.TrimSpace(reFacetRole.ReplaceAllString(facet, "")))
+	if m == nil {
+		return "", 0, 0
+	}
+
+	dates := strings.TrimSpace(m[1])
+	lower := strings.ToLower(dates)
+
+	var years []int
+	for _, y := range reYear.FindAllString(dates, 2) {
+		year, _ := strconv.Atoi(y)
+		years = append(years, year)
+	}
+
+	switch {
+	case strings.HasPrefix(lower, "fl") || strings.HasPrefix(lower, "active") || strings.Contains(lower, "b.c"):
+		return dates, 0, 0
+	case strings.HasPrefix(lower, "d.") || strings.HasPrefix(lower, "died") || strings.HasPrefix(lower, "-"):
+		return dates, 0, years[0]
+	case len(years) > 1 && strings.Contains(dates, "-"):
+		return dates, years[0], years[1]
+	}
+
+	return dates, years[0], 0
+}
+
+// Display turns an author facet such as "Twain, Mark, 1835-1910" into "Mark Twain".
+// Names with more parts, such as "Henry VIII, King of England", are left in catalog order.
+func Display(facet string) string {
+	name := strings.TrimSpace(reFacetRole.ReplaceAllString(facet, ""))
+	name = strings.TrimSpace(reLifeDates.ReplaceAllString(name, ""))
+	name = strings.TrimLeft(name, "*\"' ")
+
+	parts := strings.Split(name, ",")
+	if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+		return strings.TrimSpace(parts[1]) + " " + strings.TrimSpace(parts[0])
+	}
+
+	return name
+}
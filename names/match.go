@@ -0,0 +1,135 @@
+package names
+
+import "unicode"
+
+// Distance returns the Levenshtein edit distance between two words, counted in runes
+func Distance(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// maxEdits returns the number of edits tolerated between two words of the given length;
+// short words must match exactly
+func maxEdits(length int) int {
+	switch {
+	case length >= 8:
+		return 2
+	case length >= 5:
+		return 1
+	}
+	return 0
+}
+
+// tokenSimilarity scores how well two tokens match, from 0 to 1.  An initial matches
+// any word starting with that letter, and otherwise words within a few edits of each
+// other score by how much of the longer word is unchanged.  Numbers must match exactly.
+func tokenSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	if hasDigit(a) == true || hasDigit(b) == true {
+		return 0
+	}
+
+	ra := []rune(a)
+	rb := []rune(b)
+
+	if (len(ra) == 1 || len(rb) == 1) && ra[0] == rb[0] {
+		return 1
+	}
+
+	longest := max(len(ra), len(rb))
+
+	d := Distance(a, b)
+	if d > maxEdits(longest) {
+		return 0
+	}
+
+	return 1 - float64(d)/float64(longest)
+}
+
+// Similarity scores how much of orig is found in canon, from 0 to 1, regardless of word
+// order.  Each word of orig is paired with its best match among the unused words of canon,
+// tolerating initials and small spelling differences.  Exact matches are paired first, so
+// an initial cannot take a word that another word matches exactly.  With stripDates,
+// dates are ignored.
+func Similarity(orig, canon string, stripDates bool) float64 {
+	oWords := Tokens(orig, stripDates)
+	cWords := Tokens(canon, stripDates)
+
+	if len(oWords) == 0 || len(cWords) == 0 {
+		return 0
+	}
+
+	used := make([]bool, len(cWords))
+	matched := make([]bool, len(oWords))
+	total := 0.0
+
+	for i, ow := range oWords {
+		for j, cw := range cWords {
+			if used[j] == false && ow == cw {
+				used[j] = true
+				matched[i] = true
+				total++
+				break
+			}
+		}
+	}
+
+	for i, ow := range oWords {
+		if matched[i] == true {
+			continue
+		}
+
+		best := 0.0
+		bestIdx := -1
+
+		for j, cw := range cWords {
+			if used[j] == true {
+				continue
+			}
+			if sim := tokenSimilarity(ow, cw); sim > best {
+				best = sim
+				bestIdx = j
+			}
+		}
+
+		if bestIdx >= 0 {
+			used[bestIdx] = true
+			total += best
+		}
+	}
+
+	return total / float64(len(oWords))
+}
+
+func hasDigit(word string) bool {
+	for _, r := range word {
+		if unicode.IsDigit(r) == true {
+			return true
+		}
+	}
+	return false
+}
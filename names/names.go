@@ -0,0 +1,239 @@
+// Package names normalizes personal names and titles so that the different forms a
+// catalog, a knowledge base and an LLM use for the same name can be compared.
+package names
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	reDates = regexp.MustCompile(`,?\s*\d{4}[-\d]*`)
+	reRoles = regexp.MustCompile(`\(.*?\)`)
+)
+
+// letterFolds are letters that NFKD does not decompose into a base letter, and
+// romanization marks that carry no meaning for matching
+var letterFolds = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'ð': "d",
+	'þ': "th",
+	'ı': "i",
+	'ʹ': "", // soft sign, as in ALA-LC "Gorʹkiĭ"
+	'ʺ': "", // hard sign
+	'ʼ': "",
+}
+
+// romanizationSuffixes unify the common romanizations of Slavic name endings, so
+// "Dostoevskiĭ", "Dostoevskii", "Dostoevskiy" and "Dostoevsky" all end the same way.
+// Only the first matching suffix is replaced.
+var romanizationSuffixes = []struct {
+	from string
+	to   string
+}{
+	{"skii", "sky"},
+	{"skiy", "sky"},
+	{"skij", "sky"},
+	{"ski", "sky"},
+	{"ii", "y"},
+	{"iy", "y"},
+	{"ij", "y"},
+	{"oi", "oy"},
+}
+
+// romanizationInfixes unify common spelling variants within a romanized name, as in
+// "Dostoyevsky", "Tchaikovsky", "Prokofiev", "Fyodor" and "Maxim"
+var romanizationInfixes = []struct {
+	from string
+	to   string
+}{
+	{"tch", "ch"},
+	{"yev", "ev"},
+	{"iev", "ev"},
+	{"yo", "e"},
+	{"x", "ks"},
+}
+
+// Fold decomposes a string (NFKD), drops combining marks, lowercases it and transliterates
+// Cyrillic and Greek, so that "García Márquez" folds to "garcia marquez" and "Толстой"
+// to "tolstoi"
+func Fold(s string) string {
+	var sb strings.Builder
+
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		r = unicode.ToLower(r)
+
+		if f, ok := letterFolds[r]; ok == true {
+			sb.WriteString(f)
+			continue
+		}
+
+		if t, ok := transliterations[r]; ok == true {
+			sb.WriteString(t)
+			continue
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// Romanize maps a folded word to a common form for its romanization variants
+func Romanize(word string) string {
+	// short words are too likely to be real words that happen to match
+	if len(word) < 5 {
+		return word
+	}
+
+	for _, r := range romanizationInfixes {
+		word = strings.ReplaceAll(word, r.from, r.to)
+	}
+
+	for _, r := range romanizationSuffixes {
+		if strings.HasSuffix(word, r.from) {
+			return strings.TrimSuffix(word, r.from) + r.to
+		}
+	}
+
+	return word
+}
+
+// Tokens reduces a name or title to folded, romanized words.  Catalog decorations such as
+// leading symbols and parenthesized roles are stripped, and with stripDates, so are dates
+// (titles keep them, since a number such as "1984" may be the title).  Punctuation separates
+// words, so initials written "J.R.R." and "J. R. R." both become "j", "r", "r".
+func Tokens(s string, stripDates bool) []string {
+	// catalog-specific leading symbols (e.g., * in *Wenger, Jared)
+	s = strings.TrimLeft(s, "*\"' ")
+
+	// dates: comma followed by digits and optional dash (e.g., ", 1973-")
+	if stripDates == true {
+		s = reDates.ReplaceAllString(s, "")
+	}
+
+	// roles and descriptions in parentheses (e.g., "(editor)")
+	s = reRoles.ReplaceAllString(s, "")
+
+	words := strings.FieldsFunc(Fold(s), func(r rune) bool {
+		return unicode.IsLetter(r) == false && unicode.IsDigit(r) == false
+	})
+
+	for i, w := range words {
+		words[i] = Romanize(w)
+	}
+
+	return words
+}
+
+// Key returns a key shared by the variant forms of a name: its tokens, with dates
+// stripped, in sorted order, so "Twain, Mark, 1835-1910" and "Mark Twain" share a key
+func Key(name string) string {
+	tokens := Tokens(name, true)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// Matches reports whether two names are the same name, regardless of dates, roles, word
+// order and diacritics: their keys are equal, or would be if their initials were written
+// out, as in "Twain, M." and "Twain, Mark".  Every word must be accounted for, so
+// "Eliot, George Fielding" does not match "Eliot, George", and at least one word must
+// match in full, so initials alone never match.
+func Matches(a, b string) bool {
+	ta := Tokens(a, true)
+	tb := Tokens(b, true)
+
+	if len(ta) == 0 || len(ta) != len(tb) {
+		return false
+	}
+
+	// pair identical words first
+	used := make([]bool, len(tb))
+	var rest []string
+	full := 0
+
+	for _, wa := range ta {
+		found := false
+		for j, wb := range tb {
+			if used[j] == false && wa == wb {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if found == true {
+			full++
+		} else {
+			rest = append(rest, wa)
+		}
+	}
+
+	if full == 0 {
+		return false
+	}
+
+	// then each remaining word must be an initial of a remaining word, or the other way round
+	for _, wa := range rest {
+		found := false
+		for j, wb := range tb {
+			if used[j] == true {
+				continue
+			}
+			if isInitialOf(wa, wb) == true || isInitialOf(wb, wa) == true {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if found == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isInitialOf reports whether a is a single-letter initial of the word w
+func isInitialOf(a, w string) bool {
+	ra := []rune(a)
+	rw := []rune(w)
+
+	return len(ra) == 1 && len(rw) > 1 && ra[0] == rw[0]
+}
+
+// Covers reports whether one token list is a fuller form of the other, such as
+// "Clemens, Samuel" and "Clemens, Samuel Langhorne".  Single words are too ambiguous to match.
+func Covers(a, b []string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) < 2 {
+		return false
+	}
+
+	words := make(map[string]bool)
+	for _, w := range b {
+		words[w] = true
+	}
+
+	for _, w := range a {
+		if words[w] == false {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,266 @@
+package names
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// diacritics
+		{"García Márquez", "garcia marquez"},
+		{"Brontë, Charlotte", "bronte, charlotte"},
+		{"Le Carré, John", "le carre, john"},
+		{"Dvořák, Antonín", "dvorak, antonin"},
+		{"Dostoevskiĭ, Fyodor", "dostoevskii, fyodor"},
+
+		// letters NFKD does not decompose, and romanization marks
+		{"Straße", "strasse"},
+		{"Æsop", "aesop"},
+		{"Œuvres", "oeuvres"},
+		{"Łódź", "lodz"},
+		{"Søren Kierkegaard", "soren kierkegaard"},
+		{"Gorʹkiĭ, Maksim", "gorkii, maksim"},
+		{"ﬁsh", "fish"},
+
+		// Cyrillic
+		{"Толстой, Лев", "tolstoi, lev"},
+		{"Достоевский, Фёдор Михайлович", "dostoevskii, fedor mikhailovich"},
+		{"Чайковский", "chaikovskii"},
+
+		// Greek
+		{"Σωκράτης", "sokrates"},
+		{"Πλάτων", "platon"},
+		{"Ἀριστοτέλης", "aristoteles"},
+
+		// unchanged apart from case
+		{"Twain, Mark, 1835-1910", "twain, mark, 1835-1910"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRomanize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// Slavic endings
+		{"dostoevskii", "dostoevsky"},
+		{"dostoevskiy", "dostoevsky"},
+		{"dostoevskij", "dostoevsky"},
+		{"dostoevsky", "dostoevsky"},
+		{"tolstoi", "tolstoy"},
+		{"tolstoy", "tolstoy"},
+		{"gorkii", "gorky"},
+
+		// spelling variants within a name
+		{"dostoyevsky", "dostoevsky"},
+		{"tchaikovsky", "chaikovsky"},
+		{"chaikovskii", "chaikovsky"},
+		{"prokofiev", "prokofev"},
+		{"fyodor", "fedor"},
+		{"maxim", "maksim"},
+
+		// short words and words without variants are left alone
+		{"lev", "lev"},
+		{"ii", "ii"},
+		{"smith", "smith"},
+		{"kafka", "kafka"},
+		{"bossi", "bossi"},
+	}
+
+	for _, tt := range tests {
+		if got := Romanize(tt.in); got != tt.want {
+			t.Errorf("Romanize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		in         string
+		stripDates bool
+		want       []string
+	}{
+		{"Twain, Mark, 1835-1910", true, []string{"twain", "mark"}},
+		{"Twain, Mark, 1835-1910", false, []string{"twain", "mark", "1835", "1910"}},
+		{"Tolkien, J. R. R. (John Ronald Reuel), 1892-1973", true, []string{"tolkien", "j", "r", "r"}},
+		{"J.R.R. Tolkien", true, []string{"j", "r", "r", "tolkien"}},
+		{"*Wenger, Jared", true, []string{"wenger", "jared"}},
+		{"Smith, John, 1950-", true, []string{"smith", "john"}},
+		{"1984", false, []string{"1984"}},
+	}
+
+	for _, tt := range tests {
+		if got := Tokens(tt.in, tt.stripDates); reflect.DeepEqual(got, tt.want) == false {
+			t.Errorf("Tokens(%q, %v) = %q, want %q", tt.in, tt.stripDates, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	// each group is one name written different ways
+	same := [][]string{
+		{"Dostoevsky, Fyodor", "Fyodor Dostoevsky", "Dostoyevsky, Fyodor", "Dostoevskiĭ, Fedor, 1821-1881", "Достоевский, Фёдор"},
+		{"García Márquez, Gabriel, 1927-2014", "Gabriel Garcia Marquez", "Garcia Marquez, Gabriel"},
+		{"Tolkien, J. R. R. (John Ronald Reuel), 1892-1973", "J.R.R. Tolkien", "Tolkien, J.R.R.", "J. R. R. Tolkien"},
+		{"Twain, Mark, 1835-1910", "Mark Twain", "Twain, Mark", "*Twain, Mark"},
+		{"Tolstoy, Lev, 1828-1910", "Толстой, Лев", "Tolstoi, Lev", "Lev Tolstoy"},
+		{"Chaikovskiĭ, Petr Ilʹich, 1840-1893", "Tchaikovsky, Petr Ilich"},
+		{"Sokrates", "Σωκράτης"},
+		{"Brontë, Charlotte, 1816-1855", "Charlotte Bronte"},
+	}
+
+	for _, group := range same {
+		want := Key(group[0])
+		for _, name := range group[1:] {
+			if got := Key(name); got != want {
+				t.Errorf("Key(%q) = %q, want %q (the key of %q)", name, got, want, group[0])
+			}
+		}
+	}
+
+	// different people, or different forms that must not be merged
+	different := [][2]string{
+		{"Eliot, George Fielding, 1894-1971", "Eliot, George, 1819-1880"},
+		{"Evans, Mary", "Evans, Mary Ann"},
+		{"Twain, M.", "Twain, Mark"},
+		{"Twain, Shania", "Twain, Mark"},
+		{"Smith, John", "Smith, Jane"},
+		{"Tolstoy, Leo", "Tolstoy, Lev"},
+		{"Eliot, T. S. (Thomas Stearns), 1888-1965", "Eliot, George"},
+	}
+
+	for _, pair := range different {
+		if Key(pair[0]) == Key(pair[1]) {
+			t.Errorf("Key(%q) == Key(%q) == %q, want different keys", pair[0], pair[1], Key(pair[0]))
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Twain, Mark, 1835-1910", "Mark Twain", true},
+		{"Twain, M.", "Twain, Mark", true},
+		{"Mark Twain", "Twain, M.", true},
+		{"Tolkien, J.R.R.", "J. R. R. Tolkien", true},
+		{"Dostoevskiĭ, Fedor, 1821-1881", "Fyodor Dostoyevsky", true},
+		{"Gabriel Garcia Marquez", "García Márquez, Gabriel", true},
+		{"Voltaire, 1694-1778", "Voltaire", true},
+		{"Arouet, François-Marie", "Francois-Marie Arouet", true},
+		{"Lewis Carroll (author)", "Carroll, Lewis, 1832-1898", true},
+
+		// every word must be accounted for
+		{"Eliot, George Fielding, 1894-1971", "Eliot, George", false},
+		{"Evans, Mary", "Evans, Mary Ann", false},
+		{"Voltaire", "Voltaire, Jean", false},
+		{"Twain, Shania", "Twain, Mark", false},
+
+		// an initial must stand for the word it is paired with
+		{"Twain, S.", "Twain, Mark", false},
+
+		// initials alone never match
+		{"M. T.", "Mark Twain", false},
+		{"", "", false},
+		{"1835-1910", "1835-1910", false},
+	}
+
+	for _, tt := range tests {
+		if got := Matches(tt.a, tt.b); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := Matches(tt.b, tt.a); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		// fuller forms of a name
+		{"Clemens, Samuel", "Clemens, Samuel Langhorne", true},
+		{"Samuel Clemens", "Clemens, Samuel Langhorne, 1835-1910", true},
+		{"Tolkien, J.R.R.", "Tolkien, J. R. R. (John Ronald Reuel)", true},
+		{"Dostoevsky, Fyodor", "Dostoevskiĭ, Fedor Mikhaĭlovich", true},
+		{"Twain, Mark", "Mark Twain", true},
+
+		// single words are too ambiguous
+		{"Voltaire", "Voltaire, 1694-1778", false},
+		{"Twain", "Twain, Mark", false},
+
+		// words that are not in the other name
+		{"Clemens, Samuel", "Clemens, Roger", false},
+		{"Twain, M.", "Twain, Mark", false},
+		{"Tolstoy, Leo", "Tolstoy, Lev Nikolaevich", false},
+	}
+
+	for _, tt := range tests {
+		if got := Covers(Tokens(tt.a, true), Tokens(tt.b, true)); got != tt.want {
+			t.Errorf("Covers(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := Covers(Tokens(tt.b, true), Tokens(tt.a, true)); got != tt.want {
+			t.Errorf("Covers(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		orig, canon string
+		stripDates  bool
+		want        float64
+	}{
+		// romanization, diacritics and initials
+		{"Dostoevsky, Fyodor", "Dostoevskiĭ, Fedor, 1821-1881", true, 1},
+		{"Dostoevsky", "Достоевский, Фёдор", true, 1},
+		{"Gabriel Garcia Marquez", "García Márquez, Gabriel, 1927-2014", true, 1},
+		{"J.R.R. Tolkien", "Tolkien, J. R. R. (John Ronald Reuel), 1892-1973", true, 1},
+		{"J. Smith", "Smith, John", true, 1},
+		{"Tolstoy, Lev", "Толстой, Лев", true, 1},
+		{"Sokrates", "Σωκράτης", true, 1},
+
+		// word order, and how much of orig is found in canon
+		{"Mark Twain", "Twain, Mark", true, 1},
+		{"Ernest Hemingway", "Hemingway, Ernest, 1899-1961", true, 1},
+		{"Kafka", "Kafka, Franz", true, 1},
+		{"Eliot, George Fielding", "Eliot, George", true, 2.0 / 3},
+
+		// small spelling differences
+		{"Hemingwya, Ernest", "Hemingway, Ernest", true, (1 + (1 - 2.0/9)) / 2},
+
+		// dates count only when they are kept
+		{"Orwell, George, 1903-1950", "Orwell, George, 1850-1900", true, 1},
+		{"Orwell, George, 1903-1950", "Orwell, George, 1850-1900", false, 0.5},
+		{"1984", "1984 / George Orwell", false, 1},
+		{"1984", "Nineteen eighty-four", false, 0},
+
+		// different people
+		{"John Smith", "Smith, Jane", true, 0.5},
+		{"Twain, Shania", "Twain, Mark", true, 0.5},
+		{"Lee, Harper", "Lee, Stan", true, 0.5},
+		{"Tolstoy, Leo", "Tolstoy, Lev", true, 0.5},
+		{"Twain, S.", "Twain, Mark", true, 0.5},
+		{"Mark Twain", "", true, 0},
+		{"", "Twain, Mark", true, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Similarity(tt.orig, tt.canon, tt.stripDates); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Similarity(%q, %q, %v) = %.4f, want %.4f", tt.orig, tt.canon, tt.stripDates, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package names
+
+// transliterations romanize Cyrillic and Greek letters, roughly following the
+// Library of Congress (ALA-LC) tables the catalog uses, so that "Достоевский" folds to
+// the same tokens as "Dostoevskiĭ".  Letters are looked up after NFKD decomposition and
+// lowercasing, so accented forms such as "й" and "ά" arrive as their base letters.
+var transliterations = map[rune]string{
+	// Cyrillic (Russian, with the additional Ukrainian letters)
+	'а': "a",
+	'б': "b",
+	'в': "v",
+	'г': "g",
+	'ґ': "g",
+	'д': "d",
+	'е': "e",
+	'є': "ie",
+	'ж': "zh",
+	'з': "z",
+	'и': "i",
+	'і': "i",
+	'ї': "i",
+	'к': "k",
+	'л': "l",
+	'м': "m",
+	'н': "n",
+	'о': "o",
+	'п': "p",
+	'р': "r",
+	'с': "s",
+	'т': "t",
+	'у': "u",
+	'ф': "f",
+	'х': "kh",
+	'ц': "ts",
+	'ч': "ch",
+	'ш': "sh",
+	'щ': "shch",
+	'ъ': "",
+	'ы': "y",
+	'ь': "",
+	'э': "e",
+	'ю': "iu",
+	'я': "ia",
+
+	// Greek
+	'α': "a",
+	'β': "b",
+	'γ': "g",
+	'δ': "d",
+	'ε': "e",
+	'ζ': "z",
+	'η': "e",
+	'θ': "th",
+	'ι': "i",
+	'κ': "k",
+	'λ': "l",
+	'μ': "m",
+	'ν': "n",
+	'ξ': "x",
+	'ο': "o",
+	'π': "p",
+	'ρ': "r",
+	'σ': "s",
+	'ς': "s",
+	'τ': "t",
+	'υ': "y",
+	'φ': "ph",
+	'χ': "ch",
+	'ψ': "ps",
+	'ω': "o",
+}
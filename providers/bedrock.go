@@ -5,11 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"reflect"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -89,69 +87,17 @@ func (p *BedrockProvider) Retrieve(query string, limit int, threshold float64) (
 		return nil, nil
 	}
 
-	input := &bedrockagentruntime.RetrieveInput{
-		KnowledgeBaseId: aws.String(p.KnowledgeBaseID),
-		RetrievalQuery: &types.KnowledgeBaseQuery{
-			Text: aws.String(query),
-		},
-		RetrievalConfiguration: &types.KnowledgeBaseRetrievalConfiguration{
-			VectorSearchConfiguration: &types.KnowledgeBaseVectorSearchConfiguration{
-				NumberOfResults: aws.Int32(int32(limit)),
-			},
-		},
-	}
-
-	resp, err := p.BedrockAgent.Retrieve(context.TODO(), input)
+	resp, err := p.retrieve(p.KnowledgeBaseID, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve from KB: %w", err)
 	}
 
-	minScoreThreshold := threshold
-	if minScoreThreshold <= 0 {
-		minScoreThreshold = 0.3 // default if not provided
-	}
-
-	results := []AuthorHit{}
+	hits := []AuthorHit{}
 	for _, ref := range resp.RetrievalResults {
-		hit := AuthorHit{}
-
-		// 1. Extract Author Name and Facet Label
-		hit.Name = p.extractMetadataString(ref.Metadata, "original_facet_label", "name")
-		hit.FacetLabel = hit.Name
-
-		// 2. Extract Author Bio
-		hit.Bio = p.extractMetadataString(ref.Metadata, "bio")
-
-		// 3. Capture retrieval confidence score
-		if ref.Score != nil {
-			hit.Score = *ref.Score
-		}
-
-		// Only include hits that have a valid name extracted from metadata.
-		// We avoid falling back to raw content text as it is often truncated by KB chunking.
-		if hit.Name != "" {
-			results = append(results, hit)
-		}
-	}
-
-	// Sort by score descending so the most relevant hits are first
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
-
-	// Filter out low-confidence hits below the threshold
-	filtered := make([]AuthorHit, 0, len(results))
-	for _, hit := range results {
-		if hit.Score >= minScoreThreshold {
-			filtered = append(filtered, hit)
-		} else {
-			log.Printf("[KB] Dropping low-score author hit: '%s' (score=%.3f < %.1f)", hit.Name, hit.Score, minScoreThreshold)
-		}
+		hits = append(hits, authorHit(p.metadataReader(ref.Metadata), retrievalScore(ref)))
 	}
 
-	log.Printf("[KB] Author results: %d raw -> %d after score filter (threshold=%.1f)", len(results), len(filtered), minScoreThreshold)
-
-	return filtered, nil
+	return authorResults(hits, threshold), nil
 }
 
 // RetrieveImages will query the Bedrock Knowledge Base and return relevant image metadata
@@ -162,61 +108,24 @@ func (p *BedrockProvider) RetrieveImages(query string, limit int, threshold floa
 
 	log.Printf("[KB-IMAGES] Querying KB [%s] with query [%s]", p.ImagesKnowledgeBaseID, query)
 
-	input := &bedrockagentruntime.RetrieveInput{
-		KnowledgeBaseId: aws.String(p.ImagesKnowledgeBaseID),
-		RetrievalQuery: &types.KnowledgeBaseQuery{
-			Text: aws.String(query),
-		},
-		RetrievalConfiguration: &types.KnowledgeBaseRetrievalConfiguration{
-			VectorSearchConfiguration: &types.KnowledgeBaseVectorSearchConfiguration{
-				NumberOfResults: aws.Int32(int32(limit)),
-			},
-		},
-	}
-
-	resp, err := p.BedrockAgent.Retrieve(context.TODO(), input)
+	resp, err := p.retrieve(p.ImagesKnowledgeBaseID, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve images from KB: %w", err)
 	}
 
 	log.Printf("[KB-IMAGES] Found %d raw results from KB [%s]", len(resp.RetrievalResults), p.ImagesKnowledgeBaseID)
 
-	minScoreThreshold := threshold
-	// No default for images if not provided, we keep it liberal unless specified.
-	
-	results := []ImageHit{}
+	hits := []ImageHit{}
 	for i, ref := range resp.RetrievalResults {
-		hit := ImageHit{}
-		
 		log.Printf("[KB-IMAGES] Result [%d] raw metadata: %v", i, ref.Metadata)
 		log.Printf("[KB-IMAGES] Result [%d] content snippet: %v", i, ref.Content.Text)
 
-		// Extract metadata with flexible key matching
-		hit.ID = p.extractMetadataString(ref.Metadata, "id", "image_id")
-		hit.IIIFID = p.extractMetadataString(ref.Metadata, "iiif_id")
-		hit.Title = p.extractMetadataString(ref.Metadata, "title", "title_a")
-		hit.Collection = p.extractMetadataString(ref.Metadata, "collection", "digital_collection_a")
-		if ref.Score != nil {
-			hit.Score = *ref.Score
-		}
-
+		hit := imageHit(p.metadataReader(ref.Metadata), retrievalScore(ref))
 		log.Printf("[KB-IMAGES] Result [%d] extracted: ID=[%s], Title=[%s]", i, hit.ID, hit.Title)
-
-		// Relaxing constraints: at least one of ID or Title must be present
-		if (hit.ID != "" || hit.Title != "") && hit.Score >= minScoreThreshold {
-			// If one is missing, use the other as fallback
-			if hit.ID == "" { hit.ID = "unknown" }
-			if hit.Title == "" { hit.Title = "Image Match" }
-			results = append(results, hit)
-		} else if hit.Score < minScoreThreshold {
-			log.Printf("[KB-IMAGES] Dropping low-score image hit: '%s' (score=%.3f < %.3f)", hit.Title, hit.Score, minScoreThreshold)
-		} else {
-			log.Printf("[KB-IMAGES] Warning: Skipping hit with empty ID and Title. Metadata: %v", ref.Metadata)
-		}
+		hits = append(hits, hit)
 	}
 
-	log.Printf("[KB-IMAGES] Returning %d validated results", len(results))
-	return results, nil
+	return imageResults(hits, threshold), nil
 }
 
 // RetrieveBooks will query the Bedrock Knowledge Base and return relevant book metadata
@@ -225,84 +134,72 @@ func (p *BedrockProvider) RetrieveBooks(query string, limit int, threshold float
 		return nil, nil
 	}
 
-	// Increase internal limit to allow for reranking/popularity boosting
-	internalLimit := limit * 4
-	if internalLimit < 20 {
-		internalLimit = 20
+	internalLimit := bookRetrievalLimit(limit)
+
+	log.Printf("[KB-BOOKS] Querying KB [%s] with query [%s] (limit=%d)", p.BooksKnowledgeBaseID, query, internalLimit)
+
+	resp, err := p.retrieve(p.BooksKnowledgeBaseID, query, internalLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve books from KB: %w", err)
 	}
-	if internalLimit > 100 {
-		internalLimit = 100
+
+	hits := []BookHit{}
+	for _, ref := range resp.RetrievalResults {
+		hits = append(hits, bookHit(p.metadataReader(ref.Metadata), retrievalScore(ref)))
 	}
 
-	log.Printf("[KB-BOOKS] Querying KB [%s] with query [%s] (limit=%d)", p.BooksKnowledgeBaseID, query, internalLimit)
+	return bookResults(hits, limit, threshold), nil
+}
 
+// retrieve runs a vector search of a knowledge base
+func (p *BedrockProvider) retrieve(knowledgeBaseID string, query string, limit int) (*bedrockagentruntime.RetrieveOutput, error) {
 	input := &bedrockagentruntime.RetrieveInput{
-		KnowledgeBaseId: aws.String(p.BooksKnowledgeBaseID),
+		KnowledgeBaseId: aws.String(knowledgeBaseID),
 		RetrievalQuery: &types.KnowledgeBaseQuery{
 			Text: aws.String(query),
 		},
 		RetrievalConfiguration: &types.KnowledgeBaseRetrievalConfiguration{
 			VectorSearchConfiguration: &types.KnowledgeBaseVectorSearchConfiguration{
-				NumberOfResults: aws.Int32(int32(internalLimit)),
+				NumberOfResults: aws.Int32(int32(limit)),
 			},
 		},
 	}
 
-	resp, err := p.BedrockAgent.Retrieve(context.TODO(), input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve books from KB: %w", err)
-	}
+	return p.BedrockAgent.Retrieve(context.TODO(), input)
+}
 
-	minScoreThreshold := threshold
-	if minScoreThreshold <= 0 {
-		minScoreThreshold = 0.3
+// retrievalScore returns the confidence score of a retrieval result
+func retrievalScore(ref types.KnowledgeBaseRetrievalResult) float64 {
+	if ref.Score != nil {
+		return *ref.Score
 	}
+	return 0
+}
 
-	results := []BookHit{}
-	for i, ref := range resp.RetrievalResults {
-		hit := BookHit{}
-		
-		hit.ID = p.extractMetadataString(ref.Metadata, "id")
-		hit.Title = p.extractMetadataString(ref.Metadata, "title")
-		hit.Authors = p.extractMetadataStringList(ref.Metadata, "authors")
-		hit.Description = p.extractMetadataString(ref.Metadata, "description")
-		hit.Type = p.extractMetadataString(ref.Metadata, "type")
-		hit.Rating = p.extractMetadataFloat(ref.Metadata, "rating")
-		hit.RatingCount = p.extractMetadataInt(ref.Metadata, "rating_count")
-		
-		if ref.Score != nil {
-			hit.Score = *ref.Score
-		}
+// bedrockMetadata reads the metadata of a Bedrock retrieval result
+type bedrockMetadata struct {
+	p        *BedrockProvider
+	metadata interface{}
+}
 
-		// Apply Popularity Boost: Score * (1.0 + 0.15 * log10(RatingCount + 1))
-		if hit.RatingCount > 0 {
-			boost := 0.15 * math.Log10(float64(hit.RatingCount)+1.0)
-			oldScore := hit.Score
-			hit.Score = hit.Score * (1.0 + boost)
-			if i < 5 { // Only log the top few to keep noise down
-				log.Printf("[KB-BOOKS] Applied boost to '%s': %.4f -> %.4f (count=%d)", hit.Title, oldScore, hit.Score, hit.RatingCount)
-			}
-		}
+func (p *BedrockProvider) metadataReader(metadata interface{}) metadataReader {
+	return bedrockMetadata{p: p, metadata: metadata}
+}
 
-		if (hit.ID != "" || hit.Title != "") && hit.Score >= minScoreThreshold {
-			results = append(results, hit)
-		} else if hit.Score < minScoreThreshold {
-			log.Printf("[KB-BOOKS] Dropping low-score book hit: '%s' (score=%.3f < %.3f)", hit.Title, hit.Score, minScoreThreshold)
-		}
-	}
+func (m bedrockMetadata) String(keys ...string) string {
+	return m.p.extractMetadataString(m.metadata, keys...)
+}
 
-	// Re-sort by boosted score
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
+func (m bedrockMetadata) StringList(keys ...string) []string {
+	return m.p.extractMetadataStringList(m.metadata, keys...)
+}
 
-	// Cap at requested limit
-	if len(results) > limit {
-		results = results[:limit]
-	}
+func (m bedrockMetadata) Float(keys ...string) float64 {
+	return m.p.extractMetadataFloat(m.metadata, keys...)
+}
 
-	log.Printf("[KB-BOOKS] Returning %d validated and boosted results", len(results))
-	return results, nil
+func (m bedrockMetadata) Int(keys ...string) int {
+	return m.p.extractMetadataInt(m.metadata, keys...)
 }
 
 func (p *BedrockProvider) extractMetadataStringList(metadata interface{}, keys ...string) []string {
@@ -414,17 +311,32 @@ func (p *BedrockProvider) metadataValueToString(val interface{}) string {
 
 
 
+// candidate counts asked of the model when the caller does not set them
+const (
+	defaultAuthorCandidates  = 20
+	defaultBookCandidates    = 15
+	defaultSubjectCandidates = 10
+)
+
+// candidateLimit returns the number of suggestions to ask the model for
+func candidateLimit(limit int, def int) int {
+	if limit > 0 {
+		return limit
+	}
+	return def
+}
+
 // GetAuthorSuggestions uses the Bedrock Converse API for author-specific recommendations
 func (p *BedrockProvider) GetAuthorSuggestions(query string, customPrompt string, suggContext SuggestionContextData, debug bool) (*AIResponse, error) {
 	systemPrompt := `You are an expert academic librarian. Your goal is to provide high-quality AUTHOR name suggestions based on the user's query and the provided Background Research.
  
  CORE BEHAVIOR:
  1. CANONICAL NAMES: Always return the full, recognized name of the primary author in "Last, First" format (e.g., "Doe, John").
- 2. DIVERSITY & MIXTURE: Provide a diverse list of up to 20 suggestions. This MUST include:
+ 2. DIVERSITY & MIXTURE: Provide a diverse list of up to $LIMIT suggestions. This MUST include:
     - The primary canonical author(s) mapped from the query.
     - Relevant, specific researchers/authors found in the "Background Research" hits, even if they are secondary to the main topic.
  3. QUERY ALIGNMENT: Proactively resolve partial names.
- 4. GROUNDING & FAILOVER: Even if "Background Research" is empty or contains errors, you MUST provide at least 10 canonical author suggestions based on your internal knowledge. Prioritize relevance and name similarity.
+ 4. GROUNDING & FAILOVER: Even if "Background Research" is empty or contains errors, you MUST provide at least $MINIMUM canonical author suggestions based on your internal knowledge. Prioritize relevance and name similarity.
  5. ORDERING: Return the suggestions in descending order of relevance and confidence, with the most authoritative matches first.
  6. MINIMUM VIABILITY: Prioritize authors who are likely to have multiple records. Avoid extremely niche or single-match suggestions unless they are an exact match for the query.
  7. ATTRIBUTION: For each suggestion, you MUST indicate the source:
@@ -460,20 +372,37 @@ func (p *BedrockProvider) GetAuthorSuggestions(query string, customPrompt string
  }
  START RESPONSE WITH '{' AND NOTHING ELSE.`
 
+	limit := candidateLimit(suggContext.AuthorCandidates, defaultAuthorCandidates)
+	systemPrompt = strings.ReplaceAll(systemPrompt, "$LIMIT", strconv.Itoa(limit))
+	systemPrompt = strings.ReplaceAll(systemPrompt, "$MINIMUM", strconv.Itoa((limit+1)/2))
+
 	userPrompt := ""
 	if customPrompt == "" {
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("USER QUERY: \"%s\"\n\n", query))
+		sb.WriteString(p.formatQueryFields(suggContext.QueryFields, "author"))
+		sb.WriteString(p.formatLanguage(suggContext.Language))
 		sb.WriteString("=== BACKGROUND RESEARCH ===\n")
 		if len(suggContext.KBAuthors) > 0 {
-			sb.WriteString(fmt.Sprintf("Direct Knowledge Base author hits:\n%s\n", p.formatAuthorHits(suggContext.KBAuthors)))
+			sb.WriteString(fmt.Sprintf("Direct author hits (knowledge base and catalog name search):\n%s\n", p.formatAuthorHits(suggContext.KBAuthors)))
+		}
+		if len(suggContext.GraphAuthors) > 0 {
+			sb.WriteString(fmt.Sprintf("Catalog-linked authors (co-authors and authors on the same subjects, from catalog records):\n%s\n", p.formatGraphAuthorHits(suggContext.GraphAuthors)))
 		}
 		sb.WriteString("===========================\n\n")
-		sb.WriteString("INSTRUCTION: Analyze the query intent, considering synonyms and related concepts. Provide up to 20 relevant AUTHOR names in 'suggestions' in descending order of confidence, prioritizing the authors found in the Background Research. Output MUST be ONLY the raw JSON object. NO markdown formatting. NO comments. START RESPONSE WITH '{' AND NOTHING ELSE.\n")
+		sb.WriteString(fmt.Sprintf("INSTRUCTION: Analyze the query intent, considering synonyms and related concepts. Provide up to %d relevant AUTHOR names in 'suggestions' in descending order of confidence, prioritizing the authors found in the Background Research. Output MUST be ONLY the raw JSON object. NO markdown formatting. NO comments. START RESPONSE WITH '{' AND NOTHING ELSE.\n", limit))
 		userPrompt = sb.String()
 	} else {
 		r1 := strings.ReplaceAll(customPrompt, "$QUERY", query)
-		userPrompt = strings.ReplaceAll(r1, "$SUGGESTIONS", p.formatAuthorHits(suggContext.KBAuthors))
+		r2 := strings.ReplaceAll(r1, "$FIELDS", p.formatQueryFields(suggContext.QueryFields, "author"))
+		r3 := strings.ReplaceAll(r2, "$LIMIT", strconv.Itoa(limit))
+		r4 := strings.ReplaceAll(r3, "$LANGUAGE", p.formatLanguage(suggContext.Language))
+		// templates have one research placeholder, so catalog-linked authors follow the direct hits
+		suggestions := p.formatAuthorHits(suggContext.KBAuthors)
+		if len(suggContext.GraphAuthors) > 0 {
+			suggestions += fmt.Sprintf("\nCatalog-linked authors (co-authors and authors on the same subjects, from catalog records):\n%s", p.formatGraphAuthorHits(suggContext.GraphAuthors))
+		}
+		userPrompt = strings.ReplaceAll(r4, "$SUGGESTIONS", suggestions)
 	}
 
 	return p.internalGetSuggestions(query, systemPrompt, userPrompt, debug)
@@ -485,7 +414,7 @@ func (p *BedrockProvider) GetBookSuggestions(query string, customPrompt string,
  
  CORE BEHAVIOR:
  1. TITLES: Return the canonical title of the book.
- 2. DIVERSITY & MIXTURE: Provide a diverse list of up to 15 suggestions. Include primary titles and relevant works from the Background Research.
+ 2. DIVERSITY & MIXTURE: Provide a diverse list of up to $LIMIT suggestions. Include primary titles and relevant works from the Background Research.
  3. GROUNDING: Use the Background Research hits as your primary evidence. If empty, use your internal knowledge.
  4. ATTRIBUTION: For each suggestion, indicate the source: "kb" or "llm".
  5. CATALOG ID: Use the exact catalog ID (if provided in KB hits) as the 'id' field. This ID is used to link directly to the book's catalog page. Do NOT provide a 'facet' for books.
@@ -505,20 +434,80 @@ func (p *BedrockProvider) GetBookSuggestions(query string, customPrompt string,
  }
  START RESPONSE WITH '{' AND NOTHING ELSE.`
 
+	limit := candidateLimit(suggContext.BookCandidates, defaultBookCandidates)
+	systemPrompt = strings.ReplaceAll(systemPrompt, "$LIMIT", strconv.Itoa(limit))
+
 	userPrompt := ""
 	if customPrompt == "" {
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("USER QUERY: \"%s\"\n\n", query))
+		sb.WriteString(p.formatQueryFields(suggContext.QueryFields, "book"))
+		sb.WriteString(p.formatLanguage(suggContext.Language))
 		sb.WriteString("=== BACKGROUND RESEARCH ===\n")
 		if len(suggContext.KBBooks) > 0 {
-			sb.WriteString(fmt.Sprintf("Direct Knowledge Base book/title hits:\n%s\n", p.formatBookHits(suggContext.KBBooks)))
+			sb.WriteString(fmt.Sprintf("Direct book/title hits (knowledge base and catalog title search):\n%s\n", p.formatBookHits(suggContext.KBBooks)))
 		}
 		sb.WriteString("===========================\n\n")
-		sb.WriteString("INSTRUCTION: Provide up to 15 relevant BOOK titles. Return ONLY JSON.\n")
+		sb.WriteString(fmt.Sprintf("INSTRUCTION: Provide up to %d relevant BOOK titles. Return ONLY JSON.\n", limit))
 		userPrompt = sb.String()
 	} else {
 		r1 := strings.ReplaceAll(customPrompt, "$QUERY", query)
-		userPrompt = strings.ReplaceAll(r1, "$SUGGESTIONS", p.formatBookHits(suggContext.KBBooks))
+		r2 := strings.ReplaceAll(r1, "$FIELDS", p.formatQueryFields(suggContext.QueryFields, "book"))
+		r3 := strings.ReplaceAll(r2, "$LIMIT", strconv.Itoa(limit))
+		r4 := strings.ReplaceAll(r3, "$LANGUAGE", p.formatLanguage(suggContext.Language))
+		userPrompt = strings.ReplaceAll(r4, "$SUGGESTIONS", p.formatBookHits(suggContext.KBBooks))
+	}
+
+	return p.internalGetSuggestions(query, systemPrompt, userPrompt, debug)
+}
+
+// GetSubjectSuggestions uses the Bedrock Converse API for subject heading recommendations
+func (p *BedrockProvider) GetSubjectSuggestions(query string, customPrompt string, suggContext SuggestionContextData, debug bool) (*AIResponse, error) {
+	systemPrompt := `You are an expert academic librarian and cataloger. Your goal is to suggest SUBJECT HEADINGS that would refine a topical keyword search, based on the user's query and the provided Background Research.
+ 
+ CORE BEHAVIOR:
+ 1. HEADINGS: Return subject headings in Library of Congress Subject Headings (LCSH) form, including subdivisions where useful (e.g., "Medicine, Military -- History -- 19th century").
+ 2. DIVERSITY & MIXTURE: Provide a diverse list of up to $LIMIT suggestions, from the most specific match for the query to broader related headings.
+ 3. GROUNDING: Prefer the exact headings found in the Background Research hits, which are known to exist in the catalog. If empty, use your internal knowledge.
+ 4. ATTRIBUTION: For each suggestion, indicate the source: "kb" if the heading was in the Background Research, or "llm" otherwise.
+ 5. If the query is clearly an author name or a single book title rather than a topic, return an empty suggestions list [].
+ 
+ IMPORTANT: Output MUST be ONLY the raw JSON object. When a heading is provided in the Background Research formatted as <<Value>>, use the exact text INSIDE the markers.
+ 
+ {
+   "suggestions": [
+      { 
+        "name": "Subject heading", 
+        "type": "subject",
+        "reason": "Why it refines the search",
+        "source": "kb", "score": 0.45
+      }
+   ]
+ }
+ START RESPONSE WITH '{' AND NOTHING ELSE.`
+
+	limit := candidateLimit(suggContext.SubjectCandidates, defaultSubjectCandidates)
+	systemPrompt = strings.ReplaceAll(systemPrompt, "$LIMIT", strconv.Itoa(limit))
+
+	userPrompt := ""
+	if customPrompt == "" {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("USER QUERY: \"%s\"\n\n", query))
+		sb.WriteString(p.formatQueryFields(suggContext.QueryFields, "subject"))
+		sb.WriteString(p.formatLanguage(suggContext.Language))
+		sb.WriteString("=== BACKGROUND RESEARCH ===\n")
+		if len(suggContext.SolrSubjects) > 0 {
+			sb.WriteString(fmt.Sprintf("Catalog subject headings matching the query:\n%s\n", p.formatSubjectHits(suggContext.SolrSubjects)))
+		}
+		sb.WriteString("===========================\n\n")
+		sb.WriteString(fmt.Sprintf("INSTRUCTION: Provide up to %d SUBJECT HEADINGS that refine this search. Return ONLY JSON.\n", limit))
+		userPrompt = sb.String()
+	} else {
+		r1 := strings.ReplaceAll(customPrompt, "$QUERY", query)
+		r2 := strings.ReplaceAll(r1, "$FIELDS", p.formatQueryFields(suggContext.QueryFields, "subject"))
+		r3 := strings.ReplaceAll(r2, "$LIMIT", strconv.Itoa(limit))
+		r4 := strings.ReplaceAll(r3, "$LANGUAGE", p.formatLanguage(suggContext.Language))
+		userPrompt = strings.ReplaceAll(r4, "$SUGGESTIONS", p.formatSubjectHits(suggContext.SolrSubjects))
 	}
 
 	return p.internalGetSuggestions(query, systemPrompt, userPrompt, debug)
@@ -566,7 +555,7 @@ func (p *BedrockProvider) internalGetSuggestions(query, systemPrompt, userPrompt
 	}
 
 	if resp.StopReason == sdktypes.StopReasonGuardrailIntervened {
-		return nil, fmt.Errorf("suggestion generation was blocked by safety guardrails")
+		return nil, ErrGuardrailBlocked
 	}
 
 	output := resp.Output.(*sdktypes.ConverseOutputMemberMessage).Value
@@ -815,14 +804,27 @@ func (p *BedrockProvider) formatAuthorHits(list []AuthorHit) string {
 			if len(bio) > maxBioLength {
 				bio = bio[:maxBioLength] + "..."
 			}
-			sb.WriteString(fmt.Sprintf("- AUTHOR: <<%s>> | FACET: <<%s>> | SCORE: %.4f | BIO: %s\n", cleanName, item.FacetLabel, item.Score, bio))
+			sb.WriteString(fmt.Sprintf("- AUTHOR: <<%s>> | FACET: <<%s>> | SCORE: %.4f%s | BIO: %s\n", cleanName, item.FacetLabel, item.Score, p.formatHitSources(item.Sources), bio))
 		} else {
-			sb.WriteString(fmt.Sprintf("- AUTHOR: <<%s>> | FACET: <<%s>> | SCORE: %.4f\n", cleanName, item.FacetLabel, item.Score))
+			sb.WriteString(fmt.Sprintf("- AUTHOR: <<%s>> | FACET: <<%s>> | SCORE: %.4f%s\n", cleanName, item.FacetLabel, item.Score, p.formatHitSources(item.Sources)))
 		}
 	}
 	return sb.String()
 }
 
+// formatGraphAuthorHits returns a newline-separated list of catalog-linked authors for the prompt
+func (p *BedrockProvider) formatGraphAuthorHits(list []GraphAuthorHit) string {
+	if len(list) == 0 {
+		return "[]"
+	}
+
+	var sb strings.Builder
+	for _, item := range list {
+		sb.WriteString(fmt.Sprintf("- AUTHOR: <<%s>> | FACET: <<%s>> | LINK: %s\n", item.Name, item.Name, item.Reason))
+	}
+	return sb.String()
+}
+
 // formatBookHits returns a clear list of book hits for the prompt
 func (p *BedrockProvider) formatBookHits(list []BookHit) string {
 	if len(list) == 0 {
@@ -832,14 +834,124 @@ func (p *BedrockProvider) formatBookHits(list []BookHit) string {
 	var sb strings.Builder
 	for _, item := range list {
 		authorStr := strings.Join(item.Authors, ", ")
-		sb.WriteString(fmt.Sprintf("- BOOK: <<%s>> | ID: <<%s>> | AUTHORS: [%s] | SCORE: %.4f | DESC: %s\n", 
-			item.Title, item.ID, authorStr, item.Score, item.Description))
+		sb.WriteString(fmt.Sprintf("- BOOK: <<%s>> | ID: <<%s>> | AUTHORS: [%s] | SCORE: %.4f%s | DESC: %s\n", 
+			item.Title, item.ID, authorStr, item.Score, p.formatHitSources(item.Sources), item.Description))
+	}
+	return sb.String()
+}
+
+// hitSourceLabels describe to the model how each retriever matched a hit
+var hitSourceLabels = map[string]string{
+	"kb":   "semantic",
+	"solr": "exact name/title",
+}
+
+// formatHitSources returns a prompt fragment saying how a hit was matched, or nothing
+// if it is not known
+func (p *BedrockProvider) formatHitSources(sources []string) string {
+	labels := []string{}
+	for _, source := range sources {
+		if label, ok := hitSourceLabels[source]; ok == true {
+			labels = append(labels, label)
+		}
+	}
+
+	if len(labels) == 0 {
+		return ""
+	}
+
+	return " | MATCH: " + strings.Join(labels, " + ")
+}
+
+// queryFieldGuidance tells the model how to treat a fielded search clause, by the
+// field searched and then by the type of suggestion being generated
+var queryFieldGuidance = map[string]map[string]string{
+	"author": {
+		"author":  "The user already searched for this author. Suggest co-authors, collaborators and authors of closely related work; do not suggest the searched author again.",
+		"book":    "The user searched by author. Suggest the most notable works BY this author.",
+		"subject": "The user searched by author. Suggest the subjects this author is best known for writing about.",
+	},
+	"title": {
+		"author":  "The user searched for a title. Suggest the author(s) of that work first, then authors of closely related works.",
+		"book":    "The user searched for a title. Suggest the canonical form of that title first, then closely related works.",
+		"subject": "The user searched for a title. Suggest the subjects that work covers.",
+	},
+	"subject": {
+		"author":  "The user searched a subject. Treat it as a research topic and suggest recognized authorities on it.",
+		"book":    "The user searched a subject. Suggest foundational works on that topic.",
+		"subject": "The user already searched this subject. Suggest broader, narrower and related headings rather than the same one.",
+	},
+	"series": {
+		"author":  "The user searched for a series. Suggest the author(s) of that series.",
+		"book":    "The user searched for a series. Suggest the individual titles in that series.",
+		"subject": "The user searched for a series. Suggest the subjects that series covers.",
+	},
+}
+
+// formatQueryFields describes the fielded clauses of the user's search, with guidance
+// on how each should shape suggestions of the given type.  Plain keyword searches
+// need no explanation, so nothing is returned for them.
+func (p *BedrockProvider) formatQueryFields(fields []QueryField, suggType string) string {
+	fielded := false
+	for _, f := range fields {
+		if f.Field != "keyword" {
+			fielded = true
+			break
+		}
+	}
+
+	if fielded == false {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("=== SEARCH FIELDS ===\n")
+	for _, f := range fields {
+		sb.WriteString(fmt.Sprintf("- %s: \"%s\"\n", strings.ToUpper(f.Field), f.Value))
+	}
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if guidance := queryFieldGuidance[f.Field][suggType]; guidance != "" && seen[f.Field] == false {
+			seen[f.Field] = true
+			sb.WriteString(guidance + "\n")
+		}
+	}
+	sb.WriteString("=====================\n\n")
+
+	return sb.String()
+}
+
+// formatLanguage returns a prompt block naming the language of a non-English query, so the
+// model interprets it in that language; English and undetermined queries need no block
+func (p *BedrockProvider) formatLanguage(lang QueryLanguage) string {
+	if lang.Code == "" || lang.Code == "en" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("=== QUERY LANGUAGE ===\n")
+	sb.WriteString(fmt.Sprintf("The query is in %s (%s). Interpret it in that language, not as English. ", lang.Name, lang.Code))
+	sb.WriteString("Give names and titles in the form a library catalog would hold them, and subject headings in their standard English (LCSH) form.\n")
+	sb.WriteString("======================\n\n")
+
+	return sb.String()
+}
+
+// formatSubjectHits returns a clear list of subject heading hits for the prompt
+func (p *BedrockProvider) formatSubjectHits(list []SubjectHit) string {
+	if len(list) == 0 {
+		return "[]"
+	}
+
+	var sb strings.Builder
+	for _, item := range list {
+		sb.WriteString(fmt.Sprintf("- SUBJECT: <<%s>> | RECORDS: %d\n", item.Name, item.Count))
 	}
 	return sb.String()
 }
 
 // GetDidYouMean generates a dedicated spelling correction/refinement for the query
-func (p *BedrockProvider) GetDidYouMean(query string, debug bool) (*AIDymResponse, error) {
+func (p *BedrockProvider) GetDidYouMean(query string, language QueryLanguage, debug bool) (*AIDymResponse, error) {
 	systemPrompt := `You are a linguistic expert and library metadata specialist. Your goal is to provide a corrected or refined version of the user's search query if it contains misspellings, typos, or grammatical errors.
  
  CORE BEHAVIOR:
@@ -847,6 +959,7 @@ func (p *BedrockProvider) GetDidYouMean(query string, debug bool) (*AIDymRespons
  2. REFINEMENT: If the query is poorly formatted but understandable, refine it for better search results.
  3. NO-OP: If the query is already correctly spelled and well-formatted, return null.
  4. JSON OUTPUT: You MUST return ONLY a JSON object with a single field "didYouMean".
+ 5. LANGUAGE: Correct the query in the language it is written in. NEVER translate it. A correctly spelled query in any language needs no correction: return null.
  
  { "didYouMean": "Corrected Query" } or { "didYouMean": null }
  
@@ -856,7 +969,11 @@ func (p *BedrockProvider) GetDidYouMean(query string, debug bool) (*AIDymRespons
  - ONLY return the raw JSON block.
  START RESPONSE WITH '{' AND NOTHING ELSE.`
 
-	userPrompt := fmt.Sprintf("USER QUERY: \"%s\"\n\nINSTRUCTION: Refine the query for spelling and clarity. Return ONLY JSON.", query)
+	userPrompt := fmt.Sprintf("USER QUERY: \"%s\"\n\n", query)
+	if language.Code != "" {
+		userPrompt += fmt.Sprintf("QUERY LANGUAGE: %s (%s). Do not translate the query.\n\n", language.Name, language.Code)
+	}
+	userPrompt += "INSTRUCTION: Refine the query for spelling and clarity. Return ONLY JSON."
 
 	messages := []sdktypes.Message{
 		{
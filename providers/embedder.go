@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/uvalib/virgo4-suggestor-ws/names"
+)
+
+// defaultHashDimensions is the size of hashed embeddings when none is given
+const defaultHashDimensions = 512
+
+// Embedder turns text into a vector for semantic search.  Documents and the queries
+// searched against them must be embedded by the same embedder.
+type Embedder interface {
+	// Name identifies the embedder in a local index, e.g. "hash"
+	Name() string
+
+	// Dimensions returns the length of the vectors it produces
+	Dimensions() int
+
+	// Embed returns the vector for a text
+	Embed(text string) ([]float32, error)
+}
+
+// NewEmbedder returns the embedder of a given name; dimensions of 0 use its default
+func NewEmbedder(name string, dimensions int) (Embedder, error) {
+	switch name {
+	case "hash":
+		if dimensions <= 0 {
+			dimensions = defaultHashDimensions
+		}
+		return &HashEmbedder{dimensions: dimensions}, nil
+	}
+	return nil, fmt.Errorf("unknown embedder %s", name)
+}
+
+// HashEmbedder is a local stand-in for a neural embedding model.  It hashes the folded
+// words of a text and their character trigrams into a fixed number of dimensions, so it
+// needs no model or network; texts are similar when they share words or spellings, not
+// when they share meaning.
+type HashEmbedder struct {
+	dimensions int
+}
+
+// Name returns the name of the embedder
+func (h *HashEmbedder) Name() string {
+	return "hash"
+}
+
+// Dimensions returns the length of the vectors it produces
+func (h *HashEmbedder) Dimensions() int {
+	return h.dimensions
+}
+
+// Embed returns the unit vector of the hashed features of a text
+func (h *HashEmbedder) Embed(text string) ([]float32, error) {
+	vec := make([]float32, h.dimensions)
+
+	add := func(feature string, weight float32) {
+		f := fnv.New64a()
+		f.Write([]byte(feature))
+		sum := f.Sum64()
+
+		// the top bit chooses the sign, so colliding features tend to cancel out
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vec[sum%uint64(h.dimensions)] += weight
+	}
+
+	for _, word := range names.Tokens(text, false) {
+		add("w:"+word, 1)
+
+		padded := []rune("#" + word + "#")
+		for i := 0; i+3 <= len(padded); i++ {
+			add("t:"+string(padded[i:i+3]), 0.5)
+		}
+	}
+
+	normalize(vec)
+	return vec, nil
+}
+
+// normalize scales a vector to unit length, so that cosine similarity is a dot product
+func normalize(vec []float32) {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return
+	}
+
+	norm := float32(math.Sqrt(sum))
+	for i := range vec {
+		vec[i] /= norm
+	}
+}
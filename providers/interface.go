@@ -1,5 +1,10 @@
 package providers
 
+import "errors"
+
+// ErrGuardrailBlocked is returned when a provider's safety guardrail refuses to generate suggestions
+var ErrGuardrailBlocked = errors.New("suggestion generation was blocked by safety guardrails")
+
 // AIResponse represents the structured response from the AI provider
 type AIResponse struct {
 	DidYouMean  string                 `json:"didYouMean"`
@@ -41,6 +46,17 @@ type AuthorHit struct {
 	Bio        string  `json:"bio,omitempty"`
 	FacetLabel string  `json:"facet_label,omitempty"`
 	Score      float64 `json:"score,omitempty"`
+	Sources    []string `json:"sources,omitempty"` // retrievers that found it: kb (semantic) and/or solr (lexical)
+}
+
+// GraphAuthorHit is an author linked in the catalog to an author the query is about,
+// as a co-author or through shared subjects
+type GraphAuthorHit struct {
+	Name     string  `json:"name"`      // exact catalog author facet
+	LinkedTo string  `json:"linked_to"` // the author it is linked to
+	Relation string  `json:"relation"`  // coauthor or subject
+	Reason   string  `json:"reason"`
+	Score    float64 `json:"score"` // strength of the link, from 0 to 1
 }
 
 // ImageHit contains metadata for an image from the Knowledge Base
@@ -63,13 +79,42 @@ type BookHit struct {
 	Score       float64 `json:"score,omitempty"`
 	Rating      float64 `json:"rating,omitempty"`
 	RatingCount int     `json:"rating_count,omitempty"`
+	Sources     []string `json:"sources,omitempty"` // retrievers that found it: kb (semantic) and/or solr (lexical)
+}
+
+// SubjectHit contains a subject heading found in the Solr autocomplete core
+type SubjectHit struct {
+	Name  string  `json:"name"`
+	Count int     `json:"count,omitempty"`
+	Score float64 `json:"score,omitempty"`
+}
+
+// QueryField is one fielded clause of the user's search, such as author: {twain}
+type QueryField struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// QueryLanguage is the detected language of the user's search
+type QueryLanguage struct {
+	Code string `json:"code"` // ISO 639-1 code; empty if undetermined
+	Name string `json:"name"` // English name of the language
 }
 
 // SuggestionContextData holds the gathered research from Solr and KB
 type SuggestionContextData struct {
-	KBAuthors []AuthorHit
-	KBImages  []ImageHit
-	KBBooks   []BookHit
+	KBAuthors    []AuthorHit
+	KBImages     []ImageHit
+	KBBooks      []BookHit
+	SolrSubjects []SubjectHit
+	GraphAuthors []GraphAuthorHit // authors linked in the catalog to those the query is about
+	QueryFields  []QueryField  // the fielded clauses the query was built from, if any
+	Language     QueryLanguage // the language the query is written in, if detected
+
+	// number of suggestions to ask the LLM for; zero uses the provider default
+	AuthorCandidates  int
+	BookCandidates    int
+	SubjectCandidates int
 }
 
 // AIProvider defines the interface for different AI backends
@@ -81,8 +126,12 @@ type AIProvider interface {
 	// GetBookSuggestions generates book search suggestions based on the user query and gathered context
 	GetBookSuggestions(query string, customPrompt string, suggContext SuggestionContextData, debug bool) (*AIResponse, error)
 
-	// GetDidYouMean generates a dedicated spelling correction/refinement for the query
-	GetDidYouMean(query string, debug bool) (*AIDymResponse, error)
+	// GetSubjectSuggestions generates subject heading suggestions based on the user query and gathered context
+	GetSubjectSuggestions(query string, customPrompt string, suggContext SuggestionContextData, debug bool) (*AIResponse, error)
+
+	// GetDidYouMean generates a dedicated spelling correction/refinement for the query,
+	// in the language it is written in
+	GetDidYouMean(query string, language QueryLanguage, debug bool) (*AIDymResponse, error)
 
 	// Name returns the name of the provider (e.g. "gemini", "openai")
 	Name() string
@@ -90,12 +139,6 @@ type AIProvider interface {
 	// GetModel returns the specific model ID being used
 	GetModel() string
 
-	// Retrieve will query the provider's default Knowledge Base and return relevant author metadata
-	Retrieve(query string, limit int, threshold float64) ([]AuthorHit, error)
-
-	// RetrieveImages will query the provider's Image Knowledge Base and return relevant image metadata
-	RetrieveImages(query string, limit int, threshold float64) ([]ImageHit, error)
-
-	// RetrieveBooks will query the provider's Book Knowledge Base and return relevant book metadata
-	RetrieveBooks(query string, limit int, threshold float64) ([]BookHit, error)
+	// Retriever queries the provider's knowledge bases
+	Retriever
 }
@@ -0,0 +1,330 @@
+package providers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// local index kinds, one file each; they match the knowledge bases documents are exported for
+const (
+	LocalKindAuthor = "author"
+	LocalKindBook   = "book"
+	LocalKindImage  = "image"
+)
+
+// localManifestFile names the embedder a local index was built with
+const localManifestFile = "index.json"
+
+// localIndexExt is the extension of the file holding the documents of one kind
+const localIndexExt = ".jsonl"
+
+// LocalDocument is a document in a local index: its embedding and its knowledge base metadata
+type LocalDocument struct {
+	Name     string                 `json:"name"`
+	Vector   []float32              `json:"vector"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// localManifest describes a local index
+type localManifest struct {
+	Embedder   string `json:"embedder"`
+	Dimensions int    `json:"dimensions"`
+}
+
+// localMatch is a document found by a local search, with its cosine similarity to the query
+type localMatch struct {
+	doc   *LocalDocument
+	score float64
+}
+
+// LocalRetriever answers knowledge base queries from an index of precomputed embeddings on
+// disk, so that the service can run without Bedrock: offline, in CI or on a laptop.  The
+// search is brute force, which is fast enough for indexes of tens of thousands of documents.
+// Hits are filtered by the same thresholds as the Bedrock knowledge bases.
+type LocalRetriever struct {
+	embedder Embedder
+	docs     map[string][]LocalDocument // kind -> documents, with unit vectors
+}
+
+// NewLocalRetriever loads the local index in dir.  Queries are embedded by the embedder the
+// index was built with, unless another with the same name and dimensions is given.
+func NewLocalRetriever(dir string, embedder Embedder) (*LocalRetriever, error) {
+	manifest, err := readLocalManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s has no %s", dir, localManifestFile)
+	}
+
+	if embedder == nil {
+		if embedder, err = NewEmbedder(manifest.Embedder, manifest.Dimensions); err != nil {
+			return nil, err
+		}
+	}
+	if embedder.Name() != manifest.Embedder || embedder.Dimensions() != manifest.Dimensions {
+		return nil, fmt.Errorf("index was built with %s (%d dimensions), not %s (%d dimensions)",
+			manifest.Embedder, manifest.Dimensions, embedder.Name(), embedder.Dimensions())
+	}
+
+	r := &LocalRetriever{embedder: embedder, docs: make(map[string][]LocalDocument)}
+
+	for _, kind := range []string{LocalKindAuthor, LocalKindBook, LocalKindImage} {
+		docs, err := readLocalDocuments(filepath.Join(dir, kind+localIndexExt), manifest.Dimensions)
+		if err != nil {
+			return nil, err
+		}
+		r.docs[kind] = docs
+		log.Printf("[LOCAL-KB] loaded %d %s documents from %s", len(docs), kind, dir)
+	}
+
+	return r, nil
+}
+
+// readLocalManifest reads the manifest of a local index, or nil if there is none
+func readLocalManifest(dir string) (*localManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, localManifestFile))
+	if errors.Is(err, os.ErrNotExist) == true {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest localManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", localManifestFile, err)
+	}
+
+	return &manifest, nil
+}
+
+// readLocalDocuments reads the documents of one kind; a missing file is an empty knowledge base
+func readLocalDocuments(path string, dimensions int) ([]LocalDocument, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) == true {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var docs []LocalDocument
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var doc LocalDocument
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if len(doc.Vector) != dimensions {
+			return nil, fmt.Errorf("%s:%d: vector has %d dimensions, not %d", path, line, len(doc.Vector), dimensions)
+		}
+
+		normalize(doc.Vector)
+		docs = append(docs, doc)
+	}
+
+	return docs, scanner.Err()
+}
+
+// WriteLocalIndex writes the documents of one kind to the local index in dir, replacing
+// any written before.  Every kind in an index must be embedded by the same embedder.
+func WriteLocalIndex(dir string, kind string, embedder Embedder, docs []LocalDocument) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	manifest := localManifest{Embedder: embedder.Name(), Dimensions: embedder.Dimensions()}
+
+	existing, err := readLocalManifest(dir)
+	if err != nil {
+		return err
+	}
+	if existing != nil && *existing != manifest {
+		return fmt.Errorf("%s was built with %s (%d dimensions), not %s (%d dimensions)",
+			dir, existing.Embedder, existing.Dimensions, manifest.Embedder, manifest.Dimensions)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, localManifestFile), data, 0644); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dir, kind+localIndexExt))
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for i := range docs {
+		if err := enc.Encode(&docs[i]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// search returns the limit documents of a kind most similar to the query
+func (r *LocalRetriever) search(kind string, query string, limit int) ([]localMatch, error) {
+	docs := r.docs[kind]
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	vec, err := r.embedder.Embed(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	matches := make([]localMatch, 0, len(docs))
+	for i := range docs {
+		var dot float64
+		for j, v := range docs[i].Vector {
+			dot += float64(v) * float64(vec[j])
+		}
+		matches = append(matches, localMatch{doc: &docs[i], score: dot})
+	}
+
+	// equal scores are ordered by name, so results do not depend on the order of the index
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].doc.Name < matches[j].doc.Name
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
+
+// Retrieve searches the local author documents
+func (r *LocalRetriever) Retrieve(query string, limit int, threshold float64) ([]AuthorHit, error) {
+	matches, err := r.search(LocalKindAuthor, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []AuthorHit{}
+	for _, m := range matches {
+		hits = append(hits, authorHit(localMetadata(m.doc.Metadata), m.score))
+	}
+
+	return authorResults(hits, threshold), nil
+}
+
+// RetrieveImages searches the local image documents
+func (r *LocalRetriever) RetrieveImages(query string, limit int, threshold float64) ([]ImageHit, error) {
+	matches, err := r.search(LocalKindImage, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []ImageHit{}
+	for _, m := range matches {
+		hits = append(hits, imageHit(localMetadata(m.doc.Metadata), m.score))
+	}
+
+	return imageResults(hits, threshold), nil
+}
+
+// RetrieveBooks searches the local book documents
+func (r *LocalRetriever) RetrieveBooks(query string, limit int, threshold float64) ([]BookHit, error) {
+	matches, err := r.search(LocalKindBook, query, bookRetrievalLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []BookHit{}
+	for _, m := range matches {
+		hits = append(hits, bookHit(localMetadata(m.doc.Metadata), m.score))
+	}
+
+	return bookResults(hits, limit, threshold), nil
+}
+
+// localMetadata reads the metadata of a local document, as decoded from JSON
+type localMetadata map[string]interface{}
+
+// value returns the value of the first key present, matching keys without regard to case
+func (m localMetadata) value(keys ...string) interface{} {
+	for _, key := range keys {
+		if val, ok := m[key]; ok == true {
+			return val
+		}
+		for k, val := range m {
+			if strings.EqualFold(k, key) == true {
+				return val
+			}
+		}
+	}
+	return nil
+}
+
+func (m localMetadata) String(keys ...string) string {
+	switch v := m.value(keys...).(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		if len(v) > 0 {
+			return fmt.Sprintf("%v", v[0])
+		}
+	}
+	return ""
+}
+
+func (m localMetadata) StringList(keys ...string) []string {
+	switch v := m.value(keys...).(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := []string{}
+		for _, item := range v {
+			list = append(list, fmt.Sprintf("%v", item))
+		}
+		return list
+	}
+	return nil
+}
+
+func (m localMetadata) Float(keys ...string) float64 {
+	f, _ := strconv.ParseFloat(m.String(keys...), 64)
+	return f
+}
+
+func (m localMetadata) Int(keys ...string) int {
+	return int(m.Float(keys...))
+}
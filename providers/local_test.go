@@ -0,0 +1,195 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// localDocs embeds documents of one kind, each named by its text
+func localDocs(t *testing.T, embedder Embedder, metadata ...map[string]interface{}) []LocalDocument {
+	t.Helper()
+
+	var docs []LocalDocument
+	for _, md := range metadata {
+		text := md[MetaAuthorName]
+		if text == nil {
+			text = md[MetaBookTitle]
+		}
+		vec, err := embedder.Embed(text.(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, LocalDocument{Name: text.(string), Vector: vec, Metadata: md})
+	}
+	return docs
+}
+
+func TestLocalIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	embedder, err := NewEmbedder("hash", 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authors := localDocs(t, embedder,
+		map[string]interface{}{MetaAuthorName: "Twain, Shania"},
+		map[string]interface{}{MetaAuthorName: "Bret Harte", MetaAuthorBio: "American writer"},
+		map[string]interface{}{MetaAuthorName: "Twain, Mark", MetaAuthorBio: "American humorist"},
+	)
+	books := localDocs(t, embedder,
+		map[string]interface{}{MetaBookID: "u1", MetaBookTitle: "Roughing it", MetaBookAuthors: []interface{}{"Twain, Mark"}},
+		map[string]interface{}{MetaBookID: "u2", MetaBookTitle: "Life on the Mississippi", MetaBookRatingCount: 120},
+	)
+	images := localDocs(t, embedder,
+		map[string]interface{}{MetaImageIDAlt: "i1", MetaImageTitle: "Mississippi steamboat"},
+	)
+
+	for kind, docs := range map[string][]LocalDocument{LocalKindAuthor: authors, LocalKindBook: books, LocalKindImage: images} {
+		if err := WriteLocalIndex(dir, kind, embedder, docs); err != nil {
+			t.Fatalf("WriteLocalIndex(%s): %v", kind, err)
+		}
+	}
+
+	// the embedder is recreated from the manifest
+	r, err := NewLocalRetriever(dir, nil)
+	if err != nil {
+		t.Fatalf("NewLocalRetriever: %v", err)
+	}
+
+	authorNames := func(hits []AuthorHit) []string {
+		var res []string
+		for _, h := range hits {
+			res = append(res, h.Name)
+		}
+		return res
+	}
+
+	tests := []struct {
+		threshold float64
+		want      []string
+	}{
+		// the default threshold drops Bret Harte
+		{0, []string{"Twain, Mark", "Twain, Shania"}},
+		{0.9, []string{"Twain, Mark"}},
+	}
+
+	for _, tt := range tests {
+		hits, err := r.Retrieve("mark twain", 10, tt.threshold)
+		if err != nil {
+			t.Fatalf("Retrieve: %v", err)
+		}
+		if got := authorNames(hits); reflect.DeepEqual(got, tt.want) == false {
+			t.Errorf("Retrieve(threshold %.1f) = %q, want %q", tt.threshold, got, tt.want)
+		}
+	}
+
+	hits, err := r.Retrieve("mark twain", 1, 0)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Bio != "American humorist" || hits[0].Score < 0.99 {
+		t.Errorf("Retrieve(limit 1) = %+v, want Twain, Mark with his bio and a score of 1", hits)
+	}
+
+	bookHits, err := r.RetrieveBooks("life on the mississippi", 10, 0.5)
+	if err != nil {
+		t.Fatalf("RetrieveBooks: %v", err)
+	}
+	if len(bookHits) != 1 || bookHits[0].ID != "u2" || bookHits[0].RatingCount != 120 {
+		t.Errorf("RetrieveBooks = %+v, want u2 with its rating count", bookHits)
+	}
+
+	imageHits, err := r.RetrieveImages("steamboat", 10, 0.2)
+	if err != nil {
+		t.Fatalf("RetrieveImages: %v", err)
+	}
+	if len(imageHits) != 1 || imageHits[0].ID != "i1" || imageHits[0].Title != "Mississippi steamboat" {
+		t.Errorf("RetrieveImages = %+v, want i1", imageHits)
+	}
+}
+
+func TestLocalSearchTies(t *testing.T) {
+	dir := t.TempDir()
+
+	embedder, err := NewEmbedder("hash", 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vec, err := embedder.Embed("Mark Twain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// identical vectors, written out of name order
+	var docs []LocalDocument
+	for _, name := range []string{"c", "a", "b"} {
+		docs = append(docs, LocalDocument{Name: name, Vector: vec, Metadata: map[string]interface{}{MetaAuthorName: name}})
+	}
+	if err := WriteLocalIndex(dir, LocalKindAuthor, embedder, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewLocalRetriever(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		hits, err := r.Retrieve("mark twain", 2, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.Name)
+		}
+		if want := []string{"a", "b"}; reflect.DeepEqual(got, want) == false {
+			t.Fatalf("Retrieve = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestLocalIndexErrors(t *testing.T) {
+	hash64, _ := NewEmbedder("hash", 64)
+	hash32, _ := NewEmbedder("hash", 32)
+
+	t.Run("no manifest", func(t *testing.T) {
+		_, err := NewLocalRetriever(t.TempDir(), nil)
+		if err == nil || strings.Contains(err.Error(), localManifestFile) == false {
+			t.Errorf("NewLocalRetriever = %v, want an error naming the manifest", err)
+		}
+	})
+
+	t.Run("other embedder", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := WriteLocalIndex(dir, LocalKindAuthor, hash64, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteLocalIndex(dir, LocalKindBook, hash32, nil); err == nil {
+			t.Errorf("WriteLocalIndex with 32 dimensions into a 64 dimension index succeeded")
+		}
+		if _, err := NewLocalRetriever(dir, hash32); err == nil {
+			t.Errorf("NewLocalRetriever with 32 dimensions for a 64 dimension index succeeded")
+		}
+	})
+
+	t.Run("vector dimensions", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := WriteLocalIndex(dir, LocalKindAuthor, hash64, nil); err != nil {
+			t.Fatal(err)
+		}
+		line := `{"name":"a","vector":[1,0,0],"metadata":{"name":"a"}}` + "\n"
+		if err := os.WriteFile(filepath.Join(dir, LocalKindAuthor+localIndexExt), []byte(line), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewLocalRetriever(dir, nil)
+		if err == nil || strings.Contains(err.Error(), "3 dimensions") == false {
+			t.Errorf("NewLocalRetriever = %v, want an error about the vector dimensions", err)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Knowledge base metadata keys read by the retrievers.  Documents written for the
+// knowledge base must use the same keys; MetadataSchema.Check verifies that they do.
+const (
+	// MetaAuthorFacetLabel is the exact catalog author facet a document describes
+	MetaAuthorFacetLabel = "original_facet_label"
+	// MetaAuthorName is the author's display name, used when there is no facet label
+	MetaAuthorName = "name"
+	// MetaAuthorBio is a short biography of the author
+	MetaAuthorBio = "bio"
+
+	MetaBookID          = "id"
+	MetaBookTitle       = "title"
+	MetaBookAuthors     = "authors"
+	MetaBookDescription = "description"
+	MetaBookType        = "type"
+	MetaBookRating      = "rating"
+	MetaBookRatingCount = "rating_count" // number of ratings, which boosts the score
+
+	// images are read from either key of each pair; the second is the catalog field name
+	MetaImageID            = "id"
+	MetaImageIDAlt         = "image_id"
+	MetaImageIIIFID        = "iiif_id"
+	MetaImageTitle         = "title"
+	MetaImageTitleAlt      = "title_a"
+	MetaImageCollection    = "collection"
+	MetaImageCollectionAlt = "digital_collection_a"
+)
+
+// kinds of metadata values
+const (
+	MetaKindString = "string"
+	MetaKindNumber = "number"
+	MetaKindList   = "list" // list of strings
+)
+
+// MetadataSchema describes the metadata a retriever reads from the documents of a knowledge base
+type MetadataSchema struct {
+	Keys     map[string]string // key -> kind of value
+	Required [][]string        // a document needs a value for at least one key of each group
+}
+
+// AuthorMetadata is the metadata read by Retrieve
+var AuthorMetadata = MetadataSchema{
+	Keys: map[string]string{
+		MetaAuthorFacetLabel: MetaKindString,
+		MetaAuthorName:       MetaKindString,
+		MetaAuthorBio:        MetaKindString,
+	},
+	Required: [][]string{{MetaAuthorFacetLabel, MetaAuthorName}},
+}
+
+// BookMetadata is the metadata read by RetrieveBooks
+var BookMetadata = MetadataSchema{
+	Keys: map[string]string{
+		MetaBookID:          MetaKindString,
+		MetaBookTitle:       MetaKindString,
+		MetaBookAuthors:     MetaKindList,
+		MetaBookDescription: MetaKindString,
+		MetaBookType:        MetaKindString,
+		MetaBookRating:      MetaKindNumber,
+		MetaBookRatingCount: MetaKindNumber,
+	},
+	Required: [][]string{{MetaBookID, MetaBookTitle}},
+}
+
+// ImageMetadata is the metadata read by RetrieveImages
+var ImageMetadata = MetadataSchema{
+	Keys: map[string]string{
+		MetaImageID:            MetaKindString,
+		MetaImageIDAlt:         MetaKindString,
+		MetaImageIIIFID:        MetaKindString,
+		MetaImageTitle:         MetaKindString,
+		MetaImageTitleAlt:      MetaKindString,
+		MetaImageCollection:    MetaKindString,
+		MetaImageCollectionAlt: MetaKindString,
+	},
+	Required: [][]string{{MetaImageID, MetaImageIDAlt, MetaImageTitle, MetaImageTitleAlt}},
+}
+
+// Check reports the problems the retriever would have with a document's metadata: a
+// required value that is missing, or a value of the wrong kind.  Keys the retriever does
+// not read are allowed.
+func (m MetadataSchema) Check(metadata map[string]interface{}) error {
+	var problems []string
+
+	for _, group := range m.Required {
+		found := false
+		for _, key := range group {
+			if metadataPresent(metadata[key]) == true {
+				found = true
+				break
+			}
+		}
+		if found == false {
+			problems = append(problems, fmt.Sprintf("missing %s", strings.Join(group, " or ")))
+		}
+	}
+
+	var keys []string
+	for key := range m.Keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val, ok := metadata[key]
+		if ok == false {
+			continue
+		}
+		if kind := m.Keys[key]; metadataKind(val, kind) == false {
+			problems = append(problems, fmt.Sprintf("%s is not a %s", key, kind))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// metadataPresent reports whether a metadata value is set and not empty
+func metadataPresent(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case []string:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// metadataKind reports whether a metadata value, as written or as read back from JSON,
+// is of the given kind
+func metadataKind(val interface{}, kind string) bool {
+	switch kind {
+	case MetaKindString:
+		_, ok := val.(string)
+		return ok
+	case MetaKindNumber:
+		switch val.(type) {
+		case int, int64, float64:
+			return true
+		}
+		return false
+	case MetaKindList:
+		switch v := val.(type) {
+		case []string:
+			return true
+		case []interface{}:
+			for _, item := range v {
+				if _, ok := item.(string); ok == false {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+	return false
+}
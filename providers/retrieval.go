@@ -0,0 +1,181 @@
+package providers
+
+import (
+	"log"
+	"math"
+	"sort"
+)
+
+// minimum retrieval scores used when the caller does not set them; images have none
+const (
+	defaultAuthorThreshold = 0.3
+	defaultBookThreshold   = 0.3
+)
+
+// Retriever finds knowledge base documents semantically similar to a query
+type Retriever interface {
+
+	// Retrieve will query the author knowledge base and return relevant author metadata
+	Retrieve(query string, limit int, threshold float64) ([]AuthorHit, error)
+
+	// RetrieveImages will query the image knowledge base and return relevant image metadata
+	RetrieveImages(query string, limit int, threshold float64) ([]ImageHit, error)
+
+	// RetrieveBooks will query the book knowledge base and return relevant book metadata
+	RetrieveBooks(query string, limit int, threshold float64) ([]BookHit, error)
+}
+
+// metadataReader reads the metadata of a retrieved document, returning the value of the
+// first of the keys it has
+type metadataReader interface {
+	String(keys ...string) string
+	StringList(keys ...string) []string
+	Float(keys ...string) float64
+	Int(keys ...string) int
+}
+
+// authorHit builds an author hit from a retrieved document
+func authorHit(md metadataReader, score float64) AuthorHit {
+	name := md.String(MetaAuthorFacetLabel, MetaAuthorName)
+	return AuthorHit{
+		Name:       name,
+		FacetLabel: name,
+		Bio:        md.String(MetaAuthorBio),
+		Score:      score,
+	}
+}
+
+// imageHit builds an image hit from a retrieved document
+func imageHit(md metadataReader, score float64) ImageHit {
+	return ImageHit{
+		ID:         md.String(MetaImageID, MetaImageIDAlt),
+		IIIFID:     md.String(MetaImageIIIFID),
+		Title:      md.String(MetaImageTitle, MetaImageTitleAlt),
+		Collection: md.String(MetaImageCollection, MetaImageCollectionAlt),
+		Score:      score,
+	}
+}
+
+// bookHit builds a book hit from a retrieved document
+func bookHit(md metadataReader, score float64) BookHit {
+	return BookHit{
+		ID:          md.String(MetaBookID),
+		Title:       md.String(MetaBookTitle),
+		Authors:     md.StringList(MetaBookAuthors),
+		Description: md.String(MetaBookDescription),
+		Type:        md.String(MetaBookType),
+		Rating:      md.Float(MetaBookRating),
+		RatingCount: md.Int(MetaBookRatingCount),
+		Score:       score,
+	}
+}
+
+// bookRetrievalLimit returns the number of book documents to retrieve for a limit: more
+// than asked for, so that popularity boosting can reorder them
+func bookRetrievalLimit(limit int) int {
+	return min(max(limit*4, 20), 100)
+}
+
+// authorResults keeps the author hits with a name, best first, that meet the threshold.
+// Hits without a name are dropped rather than named from the document text, which is often
+// truncated by chunking.
+func authorResults(hits []AuthorHit, threshold float64) []AuthorHit {
+	minScoreThreshold := threshold
+	if minScoreThreshold <= 0 {
+		minScoreThreshold = defaultAuthorThreshold
+	}
+
+	results := []AuthorHit{}
+	for _, hit := range hits {
+		if hit.Name != "" {
+			results = append(results, hit)
+		}
+	}
+
+	// Sort by score descending so the most relevant hits are first
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	// Filter out low-confidence hits below the threshold
+	filtered := make([]AuthorHit, 0, len(results))
+	for _, hit := range results {
+		if hit.Score >= minScoreThreshold {
+			filtered = append(filtered, hit)
+		} else {
+			log.Printf("[KB] Dropping low-score author hit: '%s' (score=%.3f < %.1f)", hit.Name, hit.Score, minScoreThreshold)
+		}
+	}
+
+	log.Printf("[KB] Author results: %d raw -> %d after score filter (threshold=%.1f)", len(results), len(filtered), minScoreThreshold)
+
+	return filtered
+}
+
+// imageResults keeps the image hits with an ID or a title that meet the threshold, filling
+// in whichever of the two is missing
+func imageResults(hits []ImageHit, threshold float64) []ImageHit {
+	// No default for images if not provided, we keep it liberal unless specified.
+	minScoreThreshold := threshold
+
+	results := []ImageHit{}
+	for _, hit := range hits {
+		if (hit.ID != "" || hit.Title != "") && hit.Score >= minScoreThreshold {
+			if hit.ID == "" {
+				hit.ID = "unknown"
+			}
+			if hit.Title == "" {
+				hit.Title = "Image Match"
+			}
+			results = append(results, hit)
+		} else if hit.Score < minScoreThreshold {
+			log.Printf("[KB-IMAGES] Dropping low-score image hit: '%s' (score=%.3f < %.3f)", hit.Title, hit.Score, minScoreThreshold)
+		} else {
+			log.Printf("[KB-IMAGES] Warning: Skipping hit with empty ID and Title")
+		}
+	}
+
+	log.Printf("[KB-IMAGES] Returning %d validated results", len(results))
+	return results
+}
+
+// bookResults boosts book hits by popularity, keeps those with an ID or a title that meet
+// the threshold, and returns the best of them up to the limit
+func bookResults(hits []BookHit, limit int, threshold float64) []BookHit {
+	minScoreThreshold := threshold
+	if minScoreThreshold <= 0 {
+		minScoreThreshold = defaultBookThreshold
+	}
+
+	results := []BookHit{}
+	for i, hit := range hits {
+		// Apply Popularity Boost: Score * (1.0 + 0.15 * log10(RatingCount + 1))
+		if hit.RatingCount > 0 {
+			boost := 0.15 * math.Log10(float64(hit.RatingCount)+1.0)
+			oldScore := hit.Score
+			hit.Score = hit.Score * (1.0 + boost)
+			if i < 5 { // Only log the top few to keep noise down
+				log.Printf("[KB-BOOKS] Applied boost to '%s': %.4f -> %.4f (count=%d)", hit.Title, oldScore, hit.Score, hit.RatingCount)
+			}
+		}
+
+		if (hit.ID != "" || hit.Title != "") && hit.Score >= minScoreThreshold {
+			results = append(results, hit)
+		} else if hit.Score < minScoreThreshold {
+			log.Printf("[KB-BOOKS] Dropping low-score book hit: '%s' (score=%.3f < %.3f)", hit.Title, hit.Score, minScoreThreshold)
+		}
+	}
+
+	// Re-sort by boosted score
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	// Cap at requested limit
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	log.Printf("[KB-BOOKS] Returning %d validated and boosted results", len(results))
+	return results
+}